@@ -0,0 +1,66 @@
+package ruadan
+
+import "sync"
+
+// accessLog records which fields of a Configuration have been read
+type accessLog struct {
+	mu   sync.Mutex
+	read map[string]int
+}
+
+// TrackAccess starts recording the fields of a Configuration built by BuildConfig that are read through Get, the Get
+// methods, GetAll and Unmarshal, so options nothing reads any more can be found with Unused. Copies of the
+// Configuration made after TrackAccess share the record, and it is safe to read from several goroutines
+func (c *Configuration) TrackAccess() {
+	if c.access == nil {
+		c.access = &accessLog{read: map[string]int{}}
+	}
+}
+
+// Unused returns the fields of the Configuration that haven't been read since TrackAccess, in the order they were
+// built, or nil when access isn't tracked. Log it after a program has run for a while to find options to prune
+func (c *Configuration) Unused() []string {
+	if c.access == nil {
+		return nil
+	}
+
+	c.access.mu.Lock()
+	defer c.access.mu.Unlock()
+
+	unused := []string{}
+	for _, name := range c.Fields() {
+		if c.access.read[name] == 0 {
+			unused = append(unused, name)
+		}
+	}
+
+	return unused
+}
+
+// Reads returns how many times each field of the Configuration has been read since TrackAccess, keyed by name, or nil
+// when access isn't tracked
+func (c *Configuration) Reads() map[string]int {
+	if c.access == nil {
+		return nil
+	}
+
+	c.access.mu.Lock()
+	defer c.access.mu.Unlock()
+
+	reads := make(map[string]int, len(c.access.read))
+	for name, n := range c.access.read {
+		reads[name] = n
+	}
+
+	return reads
+}
+
+func (a *accessLog) mark(name string) {
+	if a == nil {
+		return
+	}
+
+	a.mu.Lock()
+	a.read[name]++
+	a.mu.Unlock()
+}
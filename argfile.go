@@ -0,0 +1,86 @@
+package ruadan
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// WithArgFiles expands every @path cli arg before -- into the args read from the file at path, the response file
+// convention used when a command line is too long for the OS or CI system. Each line holds one flag, either -name=value
+// or -name value, where the value runs to the end of the line and can be quoted. Blank lines and lines starting with
+// # are skipped, and any other line is passed on as a single arg. Response files cannot include other response files
+func WithArgFiles() LoadOptions {
+	return func(o *LoadOption) { o.argFiles = true }
+}
+
+// expandArgs returns opt.args with the response files replaced by their contents when WithArgFiles is set
+func expandArgs(opt *LoadOption) ([]string, error) {
+	if !opt.argFiles {
+		return opt.args, nil
+	}
+
+	var args []string
+	for i, arg := range opt.args {
+		if arg == "--" {
+			return append(args, opt.args[i:]...), nil
+		}
+
+		if len(arg) < 2 || arg[0] != '@' {
+			args = append(args, arg)
+			continue
+		}
+
+		file, err := readArgFile(opt.fsys, arg[1:])
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, file...)
+	}
+
+	return args, nil
+}
+
+func readArgFile(fsys fs.FS, path string) ([]string, error) {
+	r, err := openFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var args []string
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "@") {
+			return nil, fmt.Errorf("%s: line %d: response files cannot include %s", path, line, text)
+		}
+
+		if i := strings.IndexAny(text, " \t"); i > 0 && text[0] == '-' && !strings.Contains(text[:i], "=") {
+			args = append(args, text[:i], unquoteArg(strings.TrimSpace(text[i:])))
+			continue
+		}
+
+		args = append(args, text)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return args, nil
+}
+
+// unquoteArg drops one pair of matching single or double quotes around s
+func unquoteArg(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	return s
+}
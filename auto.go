@@ -0,0 +1,26 @@
+package ruadan
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// startTime is when the process started, or near enough: when the package was initialized
+var startTime = time.Now()
+
+// lookupAuto returns the value of an auto: tag, which describes the running process. The keys are hostname, pid and
+// starttime, which is formatted as RFC 3339
+func lookupAuto(key string) (string, bool) {
+	switch key {
+	case "hostname":
+		name, err := os.Hostname()
+		return name, err == nil && name != ""
+	case "pid":
+		return strconv.Itoa(os.Getpid()), true
+	case "starttime":
+		return startTime.Format(time.RFC3339Nano), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,170 @@
+package ruadan
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type base64Config struct {
+	Cert string `envconfig:"CERT" json:"cert"`
+	Key  []byte `envconfig:"KEY" json:"key"`
+	// Token is a secret, so it isn't set by flag.StringVar
+	Token string `envconfig:"TOKEN" json:"token" secret:"true"`
+}
+
+func TestBase64(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		files   map[string]string
+		want    base64Config
+		wantErr string
+	}{
+		{
+			name: "string from env",
+			env:  map[string]string{"CERT": "base64:aGVsbG8="},
+			want: base64Config{Cert: "hello"},
+		},
+		{
+			name: "bytes from env",
+			env:  map[string]string{"KEY": "base64:AAECAw=="},
+			want: base64Config{Key: []byte{0, 1, 2, 3}},
+		},
+		{
+			name: "string from cli",
+			args: []string{"-cert", "base64:aGVsbG8="},
+			want: base64Config{Cert: "hello"},
+		},
+		{
+			name: "bytes from cli",
+			args: []string{"-key=base64:AAECAw=="},
+			want: base64Config{Key: []byte{0, 1, 2, 3}},
+		},
+		{
+			name:  "string from file",
+			files: map[string]string{"config.json": `{"cert": "base64:aGVsbG8="}`},
+			want:  base64Config{Cert: "hello"},
+		},
+		{
+			name:  "bytes from file",
+			files: map[string]string{"config.json": `{"key": "base64:AAECAw=="}`},
+			want:  base64Config{Key: []byte{0, 1, 2, 3}},
+		},
+		{
+			name: "no prefix is kept as given",
+			env:  map[string]string{"CERT": "aGVsbG8=", "KEY": "raw"},
+			want: base64Config{Cert: "aGVsbG8=", Key: []byte("raw")},
+		},
+		{
+			name: "cli beats env",
+			env:  map[string]string{"CERT": "base64:ZW52"},
+			args: []string{"-cert", "base64:Y2xp"},
+			want: base64Config{Cert: "cli"},
+		},
+		{
+			name:    "invalid string from env",
+			env:     map[string]string{"CERT": "base64:not base64"},
+			wantErr: "CERT",
+		},
+		{
+			name:    "invalid bytes from env",
+			env:     map[string]string{"KEY": "base64:@@"},
+			wantErr: "KEY",
+		},
+		{
+			name:    "invalid string from cli",
+			args:    []string{"-cert", "base64:@@"},
+			wantErr: "CERT",
+		},
+		{
+			name:    "invalid bytes from cli",
+			args:    []string{"-key", "base64:@@"},
+			wantErr: "KEY",
+		},
+		{
+			name: "secret from cli",
+			args: []string{"-token", "base64:aGVsbG8="},
+			want: base64Config{Token: "hello"},
+		},
+		{
+			name:    "invalid secret from cli",
+			args:    []string{"-token", "base64:@@"},
+			wantErr: "TOKEN",
+		},
+		{
+			name:    "invalid string from file",
+			files:   map[string]string{"config.json": `{"cert": "base64:@@"}`},
+			wantErr: "CERT",
+		},
+		{
+			name:    "invalid bytes from file",
+			files:   map[string]string{"config.json": `{"key": "base64:@@"}`},
+			wantErr: "KEY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []LoadOptions
+			if tt.files != nil {
+				options = append(options, WithConfigFile("config.json"))
+			}
+
+			var cfg base64Config
+			err := testLoad(&cfg, tt.env, tt.args, tt.files, options...)
+			if tt.wantErr != "" {
+				var fe *FieldError
+				if !errors.As(err, &fe) {
+					t.Fatalf("expected a FieldError, got %v", err)
+				}
+				if fe.Key != tt.wantErr {
+					t.Errorf("FieldError is for %s, want %s", fe.Key, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("loaded %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []byte
+		wantErr bool
+	}{
+		{value: "", want: []byte{}},
+		{value: "plain", want: []byte("plain")},
+		{value: "base64:", want: []byte{}},
+		{value: "base64:AAECAw==", want: []byte{0, 1, 2, 3}},
+		{value: "base64:aGVsbG8gd29ybGQ=", want: []byte("hello world")},
+		{value: "BASE64:aGk=", want: []byte("BASE64:aGk=")},
+		{value: "base64:AAECAw", wantErr: true},
+		{value: "base64:@@", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseBytes(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != string(tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
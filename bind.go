@@ -0,0 +1,164 @@
+package ruadan
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// basicValue is a flag.Value that parses into the bool, number or string field it wraps, setting it with reflect so
+// every width of int, uint and float is written at its own size
+type basicValue struct {
+	field reflect.Value
+}
+
+func (b *basicValue) Set(value string) error {
+	return parseValue(value, b.field)
+}
+
+func (b *basicValue) String() string {
+	if b == nil || !b.field.IsValid() {
+		return ""
+	}
+
+	return fmt.Sprint(b.field.Interface())
+}
+
+// typeName is the type shown for the flag in the usage table, the same names flag.PrintDefaults uses for the flags
+// it registers itself
+func (b *basicValue) typeName() string {
+	switch {
+	case b.field.Kind() == reflect.Bool:
+		return ""
+	case b.field.Type() == durationType:
+		return "duration"
+	case isInt(b.field):
+		return "int"
+	case isUint(b.field):
+		return "uint"
+	case b.field.Kind() == reflect.Float32 || b.field.Kind() == reflect.Float64:
+		return "float"
+	default:
+		return "string"
+	}
+}
+
+// IsBoolFlag lets bool fields be set with a bare -flag, the same as flag.BoolVar
+func (b *basicValue) IsBoolFlag() bool {
+	return b.field.Kind() == reflect.Bool
+}
+
+// registerFlag adds the flag of a field. A basicValue whose field is one of the types the flag package has its own
+// flags for is registered with them instead, so fs.PrintDefaults shows its type, e.g. -port int, and leaves a zero
+// default out. They parse the same way, apart from Base64Prefix, which decodeFlags handles once the cli is parsed
+func registerFlag(fs *flag.FlagSet, v flag.Value, field reflect.Value, name, usage string) {
+	b, basic := v.(*basicValue)
+	if !basic {
+		fs.Var(v, name, usage)
+		return
+	}
+
+	switch p := field.Addr().Interface().(type) {
+	case *bool:
+		fs.BoolVar(p, name, *p, usage)
+	case *int:
+		fs.IntVar(p, name, *p, usage)
+	case *int64:
+		fs.Int64Var(p, name, *p, usage)
+	case *uint:
+		fs.UintVar(p, name, *p, usage)
+	case *uint64:
+		fs.Uint64Var(p, name, *p, usage)
+	case *float64:
+		fs.Float64Var(p, name, *p, usage)
+	case *string:
+		fs.StringVar(p, name, *p, usage)
+	case *time.Duration:
+		fs.DurationVar(p, name, *p, usage)
+	default:
+		switch {
+		case field.Kind() == reflect.Bool:
+			fs.Var(zeroBool{b}, name, usage)
+		case isNumber(field) && field.Type() != durationType:
+			fs.Var(zeroNumber{b}, name, usage)
+		default:
+			fs.Var(v, name, usage)
+		}
+	}
+}
+
+// zeroBool and zeroNumber wrap the basicValue of a bool or number the flag package has no flag for, e.g. an int8 or
+// a named type, so their zero value prints the same as a zero field and flag.PrintDefaults leaves a zero default out
+type zeroBool struct{ *basicValue }
+
+func (z zeroBool) String() string {
+	if z.basicValue == nil {
+		return "false"
+	}
+
+	return z.basicValue.String()
+}
+
+type zeroNumber struct{ *basicValue }
+
+func (z zeroNumber) String() string {
+	if z.basicValue == nil {
+		return "0"
+	}
+
+	return z.basicValue.String()
+}
+
+// secretValue wraps the flag.Value of a field tagged secret: so the flag package never prints its value, e.g. in
+// flag.PrintDefaults or the DefValue of the flag. The default is kept in def for applyPrecedence to reset the flag to
+type secretValue struct {
+	flag.Value
+	def string
+}
+
+// withSecret wraps v in a secretValue when meta is tagged secret:
+func withSecret(v flag.Value, meta fieldMeta) flag.Value {
+	if !isSecret(meta) {
+		return v
+	}
+
+	return &secretValue{Value: v, def: v.String()}
+}
+
+func (s *secretValue) String() string {
+	if s == nil || s.Value == nil || zeroDefault(s.Value.String()) {
+		return ""
+	}
+
+	return Redacted
+}
+
+func (s *secretValue) typeName() string {
+	return valueTypeName(s.Value)
+}
+
+func (s *secretValue) IsBoolFlag() bool {
+	b, ok := s.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// defValue returns the default of f, the real one for a secret rather than the Redacted one the flag package keeps
+func defValue(f *flag.Flag) string {
+	if s, ok := f.Value.(*secretValue); ok {
+		return s.def
+	}
+
+	return f.DefValue
+}
+
+// valueTypeName returns the type shown for v in the usage table, from its typeName or else the name flag.PrintDefaults
+// gives the flags the flag package registers itself
+func valueTypeName(v flag.Value) string {
+	if t, ok := v.(interface{ typeName() string }); ok {
+		return t.typeName()
+	}
+
+	name, _ := flag.UnquoteUsage(&flag.Flag{Value: v})
+	return name
+}
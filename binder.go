@@ -0,0 +1,163 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+)
+
+// Binder registers config fields by hand instead of reading them from a struct, so it needs no reflect.StructOf and
+// works under TinyGo. Each field is read from the cli and the sources, using the same parsing as
+// GetConfigFlagSet, and keeps the value it already holds as its default. Config files are not read by a Binder
+type Binder struct {
+	opt    *LoadOption
+	fields []boundField
+
+	mu sync.Mutex
+	fs *flag.FlagSet
+}
+
+type boundField struct {
+	name  string
+	env   string
+	cli   string
+	usage string
+	value flag.Value
+}
+
+// NewBinder creates a Binder with the given options. WithArgs, WithArgFiles, WithSource, WithSources, WithPrefix,
+// WithPrecedence, WithErrorHandling, WithOutput, WithName, WithUsage and WithExamples are honored, file options are
+// ignored
+func NewBinder(options ...LoadOptions) *Binder {
+	return &Binder{opt: newLoadOption(options...)}
+}
+
+// Var registers a field set by v, such as a SemVer or Rate. The name is used as the flag and, upper-cased with the
+// prefix, as the env. An empty usage is filled in the same way as clidesc: is for a struct field
+func (b *Binder) Var(v flag.Value, name, usage string) {
+	f := boundField{name: name, env: envify(name), cli: name, usage: usage, value: v}
+	if b.opt.prefix != "" {
+		f.env = b.opt.prefix + "_" + f.env
+	}
+	if f.usage == "" {
+		f.usage = "flag: " + f.cli + " or env: " + f.env
+	}
+
+	b.fields = append(b.fields, f)
+}
+
+// String registers a string field
+func (b *Binder) String(p *string, name, usage string) {
+	b.Var(stringValue{p}, name, usage)
+}
+
+// Bool registers a bool field, which can be set with a bare -name on the cli
+func (b *Binder) Bool(p *bool, name, usage string) {
+	b.Var(boolValue{p}, name, usage)
+}
+
+// Int registers an int field
+func (b *Binder) Int(p *int, name, usage string) {
+	b.Var(intValue{p}, name, usage)
+}
+
+// Int64 registers an int64 field
+func (b *Binder) Int64(p *int64, name, usage string) {
+	b.Var(int64Value{p}, name, usage)
+}
+
+// Uint registers a uint field
+func (b *Binder) Uint(p *uint, name, usage string) {
+	b.Var(uintValue{p}, name, usage)
+}
+
+// Float64 registers a float64 field
+func (b *Binder) Float64(p *float64, name, usage string) {
+	b.Var(float64Value{p}, name, usage)
+}
+
+// Duration registers a time.Duration field, read from values like 5s or 1h30m
+func (b *Binder) Duration(p *time.Duration, name, usage string) {
+	b.Var(durationValue{p}, name, usage)
+}
+
+// FlagSet returns the flag.FlagSet built by the last call to Load, or nil if nothing has been loaded
+func (b *Binder) FlagSet() *flag.FlagSet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fs
+}
+
+// Load sets every registered field from the sources and the cli, in the order set by WithPrecedence. A value that
+// fails to parse is returned as a *FieldError
+func (b *Binder) Load(ctx context.Context) error {
+	opt := b.opt
+	env, cli := layerRank(opt.precedence, Env), layerRank(opt.precedence, CLI)
+
+	fs := opt.newFlagSet()
+	for _, f := range b.fields {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if env >= 0 {
+			if _, val, ok := opt.sources.lookup(ctx, f.env); ok {
+				if err := f.value.Set(val); err != nil {
+					return &FieldError{Field: f.name, Key: f.env, Flag: f.cli, Value: val, Err: err}
+				}
+			}
+		}
+		fs.Var(f.value, f.cli, f.usage)
+	}
+
+	info := make(map[string]flagInfo, len(b.fields))
+	for _, f := range b.fields {
+		info[f.cli] = flagInfo{env: f.env}
+	}
+	opt.setUsage(fs, info)
+
+	args, err := expandArgs(opt)
+	if err != nil {
+		return err
+	}
+
+	err = fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	// undo cli values when cli isn't read, or when env ranks above it and has a value
+	if cli != 0 {
+		set := map[string]*flag.Flag{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = f })
+		for _, f := range b.fields {
+			fl, ok := set[f.cli]
+			if !ok {
+				continue
+			}
+
+			val, found := "", false
+			if env >= 0 && (cli < 0 || env < cli) {
+				_, val, found = opt.sources.lookup(ctx, f.env)
+			}
+
+			switch {
+			case found:
+			case cli < 0:
+				val = fl.DefValue
+			default:
+				continue
+			}
+
+			if err = fs.Set(fl.Name, val); err != nil {
+				return err
+			}
+		}
+	}
+
+	b.mu.Lock()
+	b.fs = fs
+	b.mu.Unlock()
+	return ctx.Err()
+}
@@ -0,0 +1,37 @@
+package ruadan
+
+import (
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	buildInfoOnce   sync.Once
+	buildInfoValues map[string]string
+)
+
+// lookupBuildInfo returns the value of a buildinfo: tag from the build info embedded in the binary. The keys are the
+// build settings, such as vcs.revision, vcs.time and vcs.modified, along with go.version, path, main.path and
+// main.version. A main.version of (devel), as under go run, counts as not set
+func lookupBuildInfo(key string) (string, bool) {
+	buildInfoOnce.Do(func() {
+		buildInfoValues = map[string]string{}
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+
+		buildInfoValues["path"] = info.Path
+		buildInfoValues["main.path"] = info.Main.Path
+		if info.Main.Version != "(devel)" {
+			buildInfoValues["main.version"] = info.Main.Version
+		}
+		buildInfoValues["go.version"] = info.GoVersion
+		for _, s := range info.Settings {
+			buildInfoValues[s.Key] = s.Value
+		}
+	})
+
+	val, ok := buildInfoValues[key]
+	return val, ok && val != ""
+}
@@ -0,0 +1,99 @@
+package ruadan
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes field type, set from values like 512, 64KB, 1.5GiB or 10M. K, M, G, T, P and E are
+// powers of 1000 with or without a trailing B, and Ki, Mi, Gi, Ti, Pi and Ei are powers of 1024, also with or
+// without a B
+type ByteSize uint64
+
+var byteUnits = []struct {
+	suffix string
+	size   uint64
+}{
+	{"EiB", 1 << 60}, {"EB", 1e18},
+	{"PiB", 1 << 50}, {"PB", 1e15},
+	{"TiB", 1 << 40}, {"TB", 1e12},
+	{"GiB", 1 << 30}, {"GB", 1e9},
+	{"MiB", 1 << 20}, {"MB", 1e6},
+	{"KiB", 1 << 10}, {"KB", 1e3},
+}
+
+// ParseByteSize parses a number of bytes with an optional unit, e.g. 64KB or 1.5GiB. Units are case-insensitive
+func ParseByteSize(s string) (ByteSize, error) {
+	str := strings.TrimSpace(s)
+	i := strings.IndexFunc(str, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	num, unit := str, ""
+	if i >= 0 {
+		num, unit = str[:i], strings.TrimSpace(str[i:])
+	}
+
+	if num == "" {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size := uint64(1)
+	if unit = strings.TrimSuffix(strings.ToUpper(unit), "B"); unit != "" {
+		size = 0
+		for _, u := range byteUnits {
+			if strings.EqualFold(strings.TrimSuffix(u.suffix, "B"), unit) {
+				size = u.size
+				break
+			}
+		}
+		if size == 0 {
+			return 0, fmt.Errorf("invalid byte size %q: unknown unit", s)
+		}
+	}
+
+	if n, err := strconv.ParseUint(num, 10, 64); err == nil {
+		if n > math.MaxUint64/size {
+			return 0, fmt.Errorf("invalid byte size %q: too large", s)
+		}
+		return ByteSize(n * size), nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	if f*float64(size) >= math.MaxUint64 {
+		return 0, fmt.Errorf("invalid byte size %q: too large", s)
+	}
+
+	return ByteSize(math.Round(f * float64(size))), nil
+}
+
+// Set parses the value as a byte size, used when reading from env or cli
+func (b *ByteSize) Set(value string) error {
+	parsed, err := ParseByteSize(value)
+	if err != nil {
+		return err
+	}
+
+	*b = parsed
+	return nil
+}
+
+// String returns the size in the unit that gives the smallest whole number, e.g. 64MiB or 1500KB, so it reads
+// easily and parses back to exactly the same size. Sizes no unit divides are written in bytes, e.g. 1025B
+func (b *ByteSize) String() string {
+	if b == nil || *b == 0 {
+		return "0"
+	}
+
+	n := uint64(*b)
+	best, suffix := n, "B"
+	for _, u := range byteUnits {
+		if n%u.size == 0 && n/u.size < best {
+			best, suffix = n/u.size, u.suffix
+		}
+	}
+
+	return strconv.FormatUint(best, 10) + suffix
+}
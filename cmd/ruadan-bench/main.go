@@ -0,0 +1,123 @@
+// Command ruadan-bench measures the time and allocations of loading large config structs with ruadan.
+//
+// Usage:
+//
+//	ruadan-bench [-fields 300] [-benchtime 1s]
+//
+// Each benchmark loads a generated struct with the given number of string, int, bool, float, duration and slice
+// fields, split between top level fields and a nested struct. Values come from a map source, a YAML file, or only
+// from default: tags.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	rd "github.com/bit-cmdr/ruadan"
+)
+
+var kinds = []struct {
+	typ reflect.Type
+	val string
+}{
+	{reflect.TypeOf(""), "value"},
+	{reflect.TypeOf(0), "42"},
+	{reflect.TypeOf(false), "true"},
+	{reflect.TypeOf(0.0), "3.14"},
+	{reflect.TypeOf(time.Duration(0)), "1m30s"},
+	{reflect.TypeOf([]string{}), "a,b,c"},
+}
+
+func main() {
+	testing.Init()
+	fields := flag.Int("fields", 300, "number of fields in the generated struct")
+	benchtime := flag.Duration("benchtime", time.Second, "minimum time to run each benchmark for")
+	flag.Parse()
+
+	if err := run(*fields, *benchtime); err != nil {
+		fmt.Fprintln(os.Stderr, "ruadan-bench:", err)
+		os.Exit(1)
+	}
+}
+
+func run(fields int, benchtime time.Duration) error {
+	if err := flag.Set("test.benchtime", benchtime.String()); err != nil {
+		return err
+	}
+
+	typ, env, yaml := generate(fields)
+
+	dir, err := ioutil.TempDir("", "ruadan-bench")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	if err = ioutil.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		return err
+	}
+
+	benchmarks := []struct {
+		name    string
+		options []rd.LoadOptions
+	}{
+		{"env", []rd.LoadOptions{rd.WithSources(rd.MapSource(env))}},
+		{"file", []rd.LoadOptions{rd.WithSources(), rd.WithConfigFile(path)}},
+		{"defaults", []rd.LoadOptions{rd.WithSources()}},
+	}
+
+	for _, bm := range benchmarks {
+		l := rd.NewLoader(append(bm.options, rd.WithArgs(nil))...)
+		if err = l.Load(context.Background(), reflect.New(typ).Interface()); err != nil {
+			return fmt.Errorf("%s: %v", bm.name, err)
+		}
+
+		res := testing.Benchmark(func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := l.Load(context.Background(), reflect.New(typ).Interface()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		fmt.Printf("Load/%s/fields=%d\t%s\t%s\n", bm.name, fields, res.String(), res.MemString())
+	}
+
+	return nil
+}
+
+// generate builds a struct type with n fields, half of them in a nested struct, along with the env values and YAML
+// file that set every field
+func generate(n int) (reflect.Type, map[string]string, string) {
+	env := map[string]string{}
+	var yaml, nested strings.Builder
+	top := make([]reflect.StructField, 0, n/2+1)
+	inner := make([]reflect.StructField, 0, n/2)
+	for i := 0; i < n; i++ {
+		k := kinds[i%len(kinds)]
+		name := fmt.Sprintf("Field%d", i)
+		f := reflect.StructField{Name: name, Type: k.typ, Tag: reflect.StructTag(`default:"` + k.val + `"`)}
+		if i%2 == 0 {
+			top = append(top, f)
+			env[strings.ToUpper(name)] = k.val
+			fmt.Fprintf(&yaml, "%s: %q\n", strings.ToLower(name), k.val)
+			continue
+		}
+
+		inner = append(inner, f)
+		env["NESTED_"+strings.ToUpper(name)] = k.val
+		fmt.Fprintf(&nested, "  %s: %q\n", strings.ToLower(name), k.val)
+	}
+
+	top = append(top, reflect.StructField{Name: "Nested", Type: reflect.StructOf(inner)})
+	return reflect.StructOf(top), env, yaml.String() + "nested:\n" + nested.String()
+}
@@ -0,0 +1,55 @@
+// Command ruadan-examples runs the documented examples in github.com/bit-cmdr/ruadan/examples in process and reports
+// which of them pass.
+//
+// Usage:
+//
+//	ruadan-examples [-v] [-run name]
+//
+// Each example loads a config struct from its own env, args and files, without reading the process env or the file
+// system. The exit code is 1 when any example fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bit-cmdr/ruadan/examples"
+)
+
+func main() {
+	verbose := flag.Bool("v", false, "print the description of every example")
+	run := flag.String("run", "", "only run examples whose name contains this text")
+	flag.Parse()
+
+	selected := []examples.Example{}
+	for _, e := range examples.All {
+		if strings.Contains(e.Name, *run) {
+			selected = append(selected, e)
+		}
+	}
+
+	failed := 0
+	for i, r := range examples.RunAll(context.Background(), selected) {
+		status := "ok"
+		if r.Err != nil {
+			status = "FAIL"
+			failed++
+		}
+
+		fmt.Printf("%-4s %s\n", status, r.Name)
+		if *verbose {
+			fmt.Printf("     %s\n", selected[i].Doc)
+		}
+		if r.Err != nil {
+			fmt.Printf("     %v\n", r.Err)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "ruadan-examples: %d of %d failed\n", failed, len(selected))
+		os.Exit(1)
+	}
+}
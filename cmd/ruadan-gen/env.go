@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type envVar struct {
+	Name  string
+	Value string
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// parseDotEnv reads KEY=value lines, skipping comments and blank lines and allowing an export prefix and quotes
+func parseDotEnv(r io.Reader) ([]envVar, error) {
+	vars := []envVar{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+
+		vars = append(vars, envVar{Name: strings.TrimSpace(line[:i]), Value: unquoteEnv(line[i+1:])})
+	}
+
+	return vars, scanner.Err()
+}
+
+// parseCompose reads the environment blocks of a docker-compose file, in either the list or the mapping form. When
+// service is set only that service is read
+func parseCompose(r io.Reader, service string) ([]envVar, error) {
+	vars := []envVar{}
+	seen := map[string]bool{}
+	current := ""
+	serviceIndent, envIndent := -1, -1
+	inServices := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		raw := strings.TrimRight(scanner.Text(), " \t\r")
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		indent := len(raw) - len(strings.TrimLeft(raw, " "))
+
+		if envIndent >= 0 && indent > envIndent {
+			name, value := composeEntry(line)
+			if name != "" && (service == "" || service == current) && !seen[name] {
+				seen[name] = true
+				vars = append(vars, envVar{Name: name, Value: value})
+			}
+			continue
+		}
+		envIndent = -1
+
+		switch {
+		case indent == 0:
+			inServices = line == "services:"
+			serviceIndent = -1
+		case inServices && (serviceIndent < 0 || indent == serviceIndent) && strings.HasSuffix(line, ":"):
+			serviceIndent = indent
+			current = strings.TrimSuffix(line, ":")
+		case inServices && line == "environment:":
+			envIndent = indent
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if service != "" && len(vars) == 0 {
+		return nil, fmt.Errorf("no environment found for service %q", service)
+	}
+
+	return vars, nil
+}
+
+func composeEntry(line string) (string, string) {
+	if strings.HasPrefix(line, "- ") {
+		entry := unquoteEnv(strings.TrimPrefix(line, "- "))
+		if i := strings.Index(entry, "="); i > 0 {
+			return entry[:i], entry[i+1:]
+		}
+		return entry, ""
+	}
+
+	if i := strings.Index(line, ":"); i > 0 {
+		return strings.TrimSpace(line[:i]), unquoteEnv(line[i+1:])
+	}
+
+	return "", ""
+}
+
+func unquoteEnv(s string) string {
+	s = strings.TrimSpace(s)
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+		return s[1 : len(s)-1]
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1]
+	default:
+		if i := strings.Index(s, " #"); i >= 0 {
+			s = strings.TrimSpace(s[:i])
+		}
+		return s
+	}
+}
+
+func envFields(vars []envVar) []genField {
+	fields := make([]genField, 0, len(vars))
+	for _, v := range vars {
+		fields = append(fields, genField{
+			Name:    goName(v.Name),
+			Type:    inferType(v.Value),
+			Tags:    []genTag{{Key: "envconfig", Value: v.Name}},
+			Default: v.Value,
+		})
+	}
+
+	return fields
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// node is a value read from a sample config file, keeping the order of keys and any comments written above them
+type node struct {
+	Key     string
+	Value   string
+	Quoted  bool
+	Comment string
+	// Children is set for mappings, Items for sequences
+	Children []*node
+	Items    []*node
+	IsMap    bool
+	IsList   bool
+}
+
+func fileFields(format string, nodes []*node) []genField {
+	fields := make([]genField, 0, len(nodes))
+	for _, n := range nodes {
+		f := genField{
+			Name:    goName(n.Key),
+			Tags:    []genTag{{Key: format, Value: n.Key}},
+			Comment: n.Comment,
+			Default: n.Value,
+		}
+
+		switch {
+		case n.IsMap:
+			f.Fields = fileFields(format, n.Children)
+		case n.IsList && len(n.Items) > 0 && n.Items[0].IsMap:
+			f.Slice = true
+			f.Fields = fileFields(format, mergeItems(n.Items))
+		case n.IsList:
+			f.Type = "[]string"
+			values := make([]string, len(n.Items))
+			for i, item := range n.Items {
+				values[i] = item.Value
+			}
+			if len(n.Items) > 0 && !n.Items[0].Quoted {
+				f.Type = "[]" + inferType(n.Items[0].Value)
+			}
+			f.Default = strings.Join(values, ",")
+		case n.Quoted:
+			f.Type = "string"
+		default:
+			f.Type = inferType(n.Value)
+		}
+
+		if n.Comment != "" && f.Fields == nil {
+			f.Tags = append(f.Tags, genTag{Key: "clidesc", Value: strings.ReplaceAll(n.Comment, "\n", " ")})
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// mergeItems combines the keys of every mapping in a sequence, so the struct generated for the items has a field
+// for any key that appears in at least one of them
+func mergeItems(items []*node) []*node {
+	merged := []*node{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		for _, child := range item.Children {
+			if !seen[child.Key] {
+				seen[child.Key] = true
+				merged = append(merged, child)
+			}
+		}
+	}
+
+	return merged
+}
+
+// parseJSONTree reads a JSON object keeping the order of its keys
+func parseJSONTree(r io.Reader) ([]*node, error) {
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	root := &node{}
+	if err := readJSONNode(d, root); err != nil {
+		return nil, err
+	}
+
+	if !root.IsMap {
+		return nil, fmt.Errorf("expected a JSON object")
+	}
+
+	return root.Children, nil
+}
+
+func readJSONNode(d *json.Decoder, n *node) error {
+	tok, err := d.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			n.IsMap = true
+			for d.More() {
+				key, err := d.Token()
+				if err != nil {
+					return err
+				}
+
+				child := &node{Key: fmt.Sprint(key)}
+				if err := readJSONNode(d, child); err != nil {
+					return err
+				}
+				n.Children = append(n.Children, child)
+			}
+		case '[':
+			n.IsList = true
+			for d.More() {
+				item := &node{}
+				if err := readJSONNode(d, item); err != nil {
+					return err
+				}
+				n.Items = append(n.Items, item)
+			}
+		}
+
+		_, err = d.Token()
+		return err
+	case string:
+		n.Value, n.Quoted = t, true
+	case json.Number:
+		n.Value = t.String()
+	case bool:
+		n.Value = strconv.FormatBool(t)
+	}
+
+	return nil
+}
+
+type yamlLine struct {
+	indent  int
+	text    string
+	comment string
+}
+
+// parseYAMLTree reads a subset of YAML keeping the order of keys and the comments written above or beside them.
+// Mappings, sequences of scalars or mappings, flow sequences and block scalars are supported
+func parseYAMLTree(r io.Reader) ([]*node, error) {
+	lines := []yamlLine{}
+	comments := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		raw := strings.TrimRight(scanner.Text(), " \t\r")
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "" || text == "---":
+			comments = comments[:0]
+			continue
+		case strings.HasPrefix(text, "#"):
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(text, "#")))
+			continue
+		}
+
+		line := yamlLine{indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text}
+		if i := commentIndex(text); i >= 0 {
+			comments = append(comments, strings.TrimSpace(text[i+1:]))
+			line.text = strings.TrimSpace(text[:i])
+		}
+		line.comment = strings.Join(comments, "\n")
+		comments = comments[:0]
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	p := &yamlTreeParser{lines: lines}
+	nodes := p.mapping(lines[0].indent)
+	if p.i < len(lines) {
+		return nil, fmt.Errorf("unexpected indentation at %q", lines[p.i].text)
+	}
+
+	return nodes, nil
+}
+
+type yamlTreeParser struct {
+	lines []yamlLine
+	i     int
+}
+
+func (p *yamlTreeParser) mapping(indent int) []*node {
+	nodes := []*node{}
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent != indent || strings.HasPrefix(line.text, "- ") || line.text == "-" {
+			break
+		}
+
+		sep := keySeparator(line.text)
+		if sep < 0 {
+			break
+		}
+
+		n := &node{Key: unquote(strings.TrimSpace(line.text[:sep])), Comment: line.comment}
+		value := strings.TrimSpace(line.text[sep+1:])
+		p.i++
+		p.value(n, value, indent)
+		nodes = append(nodes, n)
+	}
+
+	return nodes
+}
+
+func (p *yamlTreeParser) value(n *node, value string, indent int) {
+	switch {
+	case value == "":
+		if p.i >= len(p.lines) {
+			return
+		}
+
+		next := p.lines[p.i]
+		isList := strings.HasPrefix(next.text, "- ") || next.text == "-"
+		switch {
+		case isList && next.indent >= indent:
+			n.IsList = true
+			n.Items = p.sequence(next.indent)
+		case next.indent > indent:
+			n.IsMap = true
+			n.Children = p.mapping(next.indent)
+		}
+	case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+		n.Quoted = true
+		for p.i < len(p.lines) && p.lines[p.i].indent > indent {
+			p.i++
+		}
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		n.IsList = true
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				n.Items = append(n.Items, &node{Value: unquote(item), Quoted: isQuoted(item)})
+			}
+		}
+	default:
+		n.Value, n.Quoted = unquote(value), isQuoted(value)
+	}
+}
+
+func (p *yamlTreeParser) sequence(indent int) []*node {
+	items := []*node{}
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		if line.indent != indent || !(strings.HasPrefix(line.text, "- ") || line.text == "-") {
+			break
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		item := &node{Comment: line.comment}
+		if keySeparator(rest) >= 0 {
+			// the first key of a mapping item shares the line with the dash, the rest line up with it
+			p.lines[p.i] = yamlLine{indent: indent + 2, text: rest, comment: line.comment}
+			item.IsMap = true
+			item.Children = p.mapping(indent + 2)
+		} else {
+			p.i++
+			p.value(item, rest, indent)
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+func keySeparator(s string) int {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ':' && (i == len(s)-1 || s[i+1] == ' '):
+			return i
+		}
+	}
+
+	return -1
+}
+
+func commentIndex(s string) int {
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && i > 0 && (s[i-1] == ' ' || s[i-1] == '\t'):
+			return i
+		}
+	}
+
+	return -1
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0]
+}
+
+func unquote(s string) string {
+	if !isQuoted(s) {
+		return s
+	}
+
+	if s[0] == '"' {
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+	}
+
+	return s[1 : len(s)-1]
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+type genOptions struct {
+	Package  string
+	Name     string
+	Defaults bool
+}
+
+type genTag struct {
+	Key   string
+	Value string
+}
+
+type genField struct {
+	Name    string
+	Type    string
+	Tags    []genTag
+	Default string
+	Comment string
+	// Fields is set for nested structs, in which case Type is ignored. Slice makes it a slice of those structs
+	Fields []genField
+	Slice  bool
+}
+
+// generate renders the struct and formats it with gofmt
+func generate(opts genOptions, fields []genField) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "package %s\n\n", opts.Package)
+	if needsTime(fields) {
+		buf.WriteString("import \"time\"\n\n")
+	}
+
+	fmt.Fprintf(buf, "// %s was generated by ruadan-gen to be read with ruadan.GetConfigFlagSet\n", opts.Name)
+	fmt.Fprintf(buf, "type %s struct {\n", opts.Name)
+	writeFields(buf, opts, fields)
+	buf.WriteString("}\n")
+
+	return format.Source(buf.Bytes())
+}
+
+func writeFields(buf *bytes.Buffer, opts genOptions, fields []genField) {
+	names := map[string]int{}
+	for _, f := range fields {
+		name := f.Name
+		if names[name]++; names[name] > 1 {
+			name += strconv.Itoa(names[name])
+		}
+
+		if f.Comment != "" {
+			for _, line := range strings.Split(f.Comment, "\n") {
+				fmt.Fprintf(buf, "// %s\n", line)
+			}
+		}
+
+		tags := append([]genTag{}, f.Tags...)
+		if opts.Defaults && f.Default != "" && f.Fields == nil {
+			tags = append(tags, genTag{Key: "default", Value: f.Default})
+		}
+
+		parts := make([]string, len(tags))
+		for i, t := range tags {
+			parts[i] = t.Key + ":" + strconv.Quote(t.Value)
+		}
+		tag := ""
+		if len(parts) > 0 {
+			tag = " `" + strings.Join(parts, " ") + "`"
+		}
+
+		if f.Fields != nil {
+			slice := ""
+			if f.Slice {
+				slice = "[]"
+			}
+			fmt.Fprintf(buf, "%s %sstruct {\n", name, slice)
+			nested := opts
+			// items of a slice are read from JSON, so default: tags on their fields would never be used
+			nested.Defaults = opts.Defaults && !f.Slice
+			writeFields(buf, nested, f.Fields)
+			fmt.Fprintf(buf, "}%s\n", tag)
+			continue
+		}
+
+		fmt.Fprintf(buf, "%s %s%s\n", name, f.Type, tag)
+	}
+}
+
+func needsTime(fields []genField) bool {
+	for _, f := range fields {
+		if f.Type == "time.Duration" || needsTime(f.Fields) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inferType picks the Go type for a sample value
+func inferType(v string) string {
+	v = strings.TrimSpace(v)
+	switch {
+	case v == "":
+		return "string"
+	case v == "true" || v == "false":
+		return "bool"
+	}
+
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return "int"
+	}
+
+	if _, err := strconv.ParseFloat(v, 64); err == nil && strings.ContainsAny(v, ".eE") {
+		return "float64"
+	}
+
+	if _, err := time.ParseDuration(v); err == nil {
+		return "time.Duration"
+	}
+
+	if strings.Contains(v, ",") && !strings.ContainsAny(v, " =") {
+		return "[]" + inferType(strings.Split(v, ",")[0])
+	}
+
+	return "string"
+}
+
+var initialisms = map[string]string{
+	"api": "API", "aws": "AWS", "cpu": "CPU", "db": "DB", "dns": "DNS", "http": "HTTP", "https": "HTTPS", "id": "ID",
+	"ip": "IP", "json": "JSON", "jwt": "JWT", "sql": "SQL", "ssl": "SSL", "tcp": "TCP", "tls": "TLS", "ttl": "TTL",
+	"udp": "UDP", "ui": "UI", "uri": "URI", "url": "URL", "uuid": "UUID", "xml": "XML", "yaml": "YAML",
+}
+
+// goName turns a name like DB_HOST, db-host or dbHost into an exported Go identifier such as DBHost
+func goName(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	split := []string{}
+	for _, w := range words {
+		start := 0
+		runes := []rune(w)
+		for i := 1; i < len(runes); i++ {
+			if unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]) {
+				split = append(split, string(runes[start:i]))
+				start = i
+			}
+		}
+		split = append(split, string(runes[start:]))
+	}
+
+	name := ""
+	for _, w := range split {
+		lower := strings.ToLower(w)
+		if upper, ok := initialisms[lower]; ok {
+			name += upper
+			continue
+		}
+		first, size := utf8.DecodeRuneInString(lower)
+		name += string(unicode.ToUpper(first)) + lower[size:]
+	}
+
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "Field" + name
+	}
+
+	return name
+}
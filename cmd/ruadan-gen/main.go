@@ -0,0 +1,129 @@
+// Command ruadan-gen generates tagged Go config structs for use with ruadan from existing configuration.
+//
+// Usage:
+//
+//	ruadan-gen from-env [flags] <file>
+//	ruadan-gen from-file [flags] <file>
+//
+// from-env reads a .env file, or the environment block of a docker-compose file, and prints a struct with a field
+// for every variable. Types are inferred from the values and each field gets an envconfig: tag.
+//
+// from-file reads a sample YAML or JSON config file and prints a struct with a field for every key, using nested
+// structs for mappings and a yaml: or json: tag for each field. Comments above a key in a YAML file become the doc
+// comment and clidesc: tag of its field.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "ruadan-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected a command: from-env or from-file")
+	}
+
+	switch args[0] {
+	case "from-env":
+		return fromEnv(args[1:], out)
+	case "from-file":
+		return fromFile(args[1:], out)
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func fromEnv(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("from-env", flag.ExitOnError)
+	opts := genOptions{}
+	fs.StringVar(&opts.Package, "package", "main", "package name of the generated file")
+	fs.StringVar(&opts.Name, "name", "Config", "name of the generated struct")
+	fs.BoolVar(&opts.Defaults, "defaults", false, "add the values in the file as default: tags")
+	service := fs.String("service", "", "docker-compose service to read, all services are merged when empty")
+	compose := fs.Bool("compose", false, "read the file as docker-compose, detected from a .yml or .yaml extension")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("from-env expects a single file")
+	}
+
+	path := fs.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var vars []envVar
+	if *compose || isYAML(path) {
+		vars, err = parseCompose(f, *service)
+	} else {
+		vars, err = parseDotEnv(f)
+	}
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(opts, envFields(vars))
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(src)
+	return err
+}
+
+func fromFile(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("from-file", flag.ExitOnError)
+	opts := genOptions{}
+	fs.StringVar(&opts.Package, "package", "main", "package name of the generated file")
+	fs.StringVar(&opts.Name, "name", "Config", "name of the generated struct")
+	fs.BoolVar(&opts.Defaults, "defaults", false, "add the values in the file as default: tags")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return fmt.Errorf("from-file expects a single file")
+	}
+
+	path := fs.Arg(0)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	format := "yaml"
+	var nodes []*node
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+		nodes, err = parseJSONTree(f)
+	} else {
+		nodes, err = parseYAMLTree(f)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	src, err := generate(opts, fileFields(format, nodes))
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write(src)
+	return err
+}
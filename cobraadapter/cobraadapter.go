@@ -0,0 +1,110 @@
+// Package cobraadapter makes ruadan the config engine behind a github.com/spf13/cobra CLI. The fields of a config
+// struct become flags of a command, in cobra's --kebab-case style, and the struct is loaded from every ruadan layer
+// before the command runs, so env, sources, files, defaults and validation work the same as without cobra. It lives
+// in its own module so the core ruadan module stays free of dependencies
+package cobraadapter
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bit-cmdr/ruadan"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Bind adds a flag to cmd for every field of cfg, which must be a struct pointer, and loads cfg with options before
+// cmd runs, ahead of any PreRun or PreRunE it already has. A field whose ruadan flag is SERVER_PORT gets the flag
+// --server-port, with its short: tag as the shorthand, and the help of each flag names the env that also sets it.
+// Only the flags given on the command line are passed to ruadan, so they rank the same as its own cli layer
+func Bind(cmd *cobra.Command, cfg interface{}, options ...ruadan.LoadOptions) error {
+	fields, err := ruadan.Describe(cfg, options...)
+	if err != nil {
+		return err
+	}
+
+	// names maps the cobra flag of each field to its ruadan flag
+	names := make(map[string]string, len(fields))
+	flags := cmd.Flags()
+	for _, f := range fields {
+		name := flagName(f.Flag)
+		if flags.Lookup(name) != nil {
+			return fmt.Errorf("cobraadapter: %s: --%s is already a flag of %s", f.Field, name, cmd.Name())
+		}
+		if f.Short != "" && (utf8.RuneCountInString(f.Short) != 1 || flags.ShorthandLookup(f.Short) != nil) {
+			return fmt.Errorf("cobraadapter: %s: -%s is not a free single letter shorthand", f.Field, f.Short)
+		}
+
+		if f.Type == "bool" || f.Type == "*bool" {
+			flags.BoolP(name, f.Short, f.Default == "true", usage(f))
+		} else {
+			flags.StringP(name, f.Short, f.Default, usage(f))
+		}
+		names[name] = f.Flag
+	}
+
+	preE, pre := cmd.PreRunE, cmd.PreRun
+	cmd.PreRunE = func(c *cobra.Command, args []string) error {
+		if err := load(c, cfg, names, options); err != nil {
+			return err
+		}
+
+		switch {
+		case preE != nil:
+			return preE(c, args)
+		case pre != nil:
+			// cobra skips PreRun once PreRunE is set, so it is run from here
+			pre(c, args)
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// load reads cfg from every ruadan layer, with the flags set on the command line of c as the cli
+func load(c *cobra.Command, cfg interface{}, names map[string]string, options []ruadan.LoadOptions) error {
+	args := []string{}
+	c.Flags().Visit(func(f *pflag.Flag) {
+		if name, ok := names[f.Name]; ok {
+			args = append(args, "-"+name+"="+f.Value.String())
+		}
+	})
+
+	ctx := c.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	options = append(options,
+		ruadan.WithArgs(args),
+		ruadan.WithErrorHandling(flag.ContinueOnError),
+		ruadan.WithOutput(ioutil.Discard),
+	)
+
+	return ruadan.NewLoader(options...).Load(ctx, cfg)
+}
+
+// flagName turns a ruadan flag such as SERVER_PORT into the cobra style server-port
+func flagName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// usage is the help of a field's flag: its clidesc: followed by the env that also sets it
+func usage(f ruadan.FieldInfo) string {
+	env := "env " + f.Env
+	if f.Required {
+		env += ", required"
+	}
+
+	if f.Usage == "" {
+		return "(" + env + ")"
+	}
+
+	return f.Usage + " (" + env + ")"
+}
@@ -0,0 +1,13 @@
+module github.com/bit-cmdr/ruadan/cobraadapter
+
+go 1.22
+
+require (
+	github.com/bit-cmdr/ruadan v0.0.0-00010101000000-000000000000
+	github.com/spf13/cobra v1.8.1
+	github.com/spf13/pflag v1.0.5
+)
+
+require github.com/inconshreveable/mousetrap v1.1.0 // indirect
+
+replace github.com/bit-cmdr/ruadan => ..
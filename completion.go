@@ -0,0 +1,230 @@
+package ruadan
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// enumValue is the flag.Value of a field that takes one of a list of values, from a oneof rule of its validate: tag
+// or an enum: tag, so completion scripts can offer them
+type enumValue struct {
+	flag.Value
+	values []string
+}
+
+func (e *enumValue) String() string {
+	if e == nil || e.Value == nil {
+		return ""
+	}
+
+	return e.Value.String()
+}
+
+func (e *enumValue) typeName() string {
+	return valueTypeName(e.Value)
+}
+
+// withEnum wraps v in an enumValue when meta lists the values it takes. Bool flags are left as they are, as they
+// take no value on the cli
+func withEnum(v flag.Value, meta fieldMeta) flag.Value {
+	if b, ok := v.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return v
+	}
+
+	values := enumValues(meta.Tags)
+	if len(values) == 0 {
+		return v
+	}
+
+	return &enumValue{Value: v, values: values}
+}
+
+// enumValues returns the values listed by the oneof rule of the validate: tag or else the enum: tag, which is
+// comma separated and only used for completion, e.g. for an enum type whose UnmarshalText rejects anything else
+func enumValues(tags reflect.StructTag) []string {
+	for _, rule := range strings.Split(tags.Get("validate"), ",") {
+		if name, arg, ok := strings.Cut(strings.TrimSpace(rule), "="); ok && name == "oneof" {
+			return strings.Fields(arg)
+		}
+	}
+
+	if tag := tags.Get("enum"); tag != "" {
+		values := []string{}
+		for _, v := range strings.Split(tag, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+
+	return nil
+}
+
+// Shells that GenCompletion writes scripts for
+const (
+	Bash = "bash"
+	Zsh  = "zsh"
+	Fish = "fish"
+)
+
+// GenCompletion returns a completion script for shell, one of Bash, Zsh or Fish, covering every flag of fs for the
+// program named by fs.Name(), which is set with WithName. Flags of fields with a oneof rule or an enum: tag complete
+// to the values listed, other flags that take a value complete to file names in bash and zsh. The scripts are meant
+// to be sourced, or for zsh to be saved as _name somewhere in $fpath
+func GenCompletion(fs *flag.FlagSet, shell string) (string, error) {
+	flags := completionFlags(fs)
+	switch shell {
+	case Bash:
+		return bashCompletion(fs.Name(), flags), nil
+	case Zsh:
+		return zshCompletion(fs.Name(), flags), nil
+	case Fish:
+		return fishCompletion(fs.Name(), flags), nil
+	default:
+		return "", fmt.Errorf("no completion for shell %q, expected bash, zsh or fish", shell)
+	}
+}
+
+// completionFlag is what a completion script needs to know about a flag
+type completionFlag struct {
+	name   string
+	typ    string
+	usage  string
+	bool   bool
+	values []string
+}
+
+func completionFlags(fs *flag.FlagSet) []completionFlag {
+	flags := []completionFlag{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, deprecated := f.Value.(*renamedValue); deprecated {
+			return
+		}
+
+		typ, usage := flag.UnquoteUsage(f)
+		if v, ok := f.Value.(interface{ typeName() string }); ok && !strings.Contains(f.Usage, "`") {
+			typ = v.typeName()
+		}
+
+		cf := completionFlag{name: f.Name, typ: typ, usage: strings.ReplaceAll(usage, "\n", " ")}
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			cf.bool = true
+		}
+		v := f.Value
+		if s, ok := v.(*shortValue); ok {
+			v = s.Value
+		}
+		if s, ok := v.(*secretValue); ok {
+			v = s.Value
+		}
+		if e, ok := v.(*enumValue); ok {
+			cf.values = e.values
+		}
+		flags = append(flags, cf)
+	})
+
+	return flags
+}
+
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func bashCompletion(name string, flags []completionFlag) string {
+	fn := "_" + nonIdent.ReplaceAllString(name, "_") + "_complete"
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	b.WriteString("\tcase \"$prev\" in\n")
+	files := []string{}
+	for _, f := range flags {
+		switch {
+		case f.bool:
+		case len(f.values) > 0:
+			fmt.Fprintf(&b, "\t-%[1]s|--%[1]s)\n", f.name)
+			fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n\t\treturn\n\t\t;;\n",
+				quoteSh(strings.Join(f.values, " ")))
+		default:
+			files = append(files, "-"+f.name, "--"+f.name)
+		}
+	}
+	if len(files) > 0 {
+		fmt.Fprintf(&b, "\t%s)\n", strings.Join(files, "|"))
+		b.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n\t\treturn\n\t\t;;\n")
+	}
+	b.WriteString("\tesac\n")
+
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.name
+	}
+	fmt.Fprintf(&b, "\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", quoteSh(strings.Join(names, " ")))
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -F %s %s\n", fn, quoteSh(name))
+
+	return b.String()
+}
+
+func zshCompletion(name string, flags []completionFlag) string {
+	fn := "_" + nonIdent.ReplaceAllString(name, "_")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n\n", name)
+	fmt.Fprintf(&b, "%s() {\n\t_arguments", fn)
+	for _, f := range flags {
+		spec := "-" + f.name + "[" + zshEscape(f.usage) + "]"
+		switch {
+		case f.bool:
+		case len(f.values) > 0:
+			spec += ":" + zshEscape(f.typ) + ":(" + zshEscape(strings.Join(f.values, " ")) + ")"
+		default:
+			spec += ":" + zshEscape(f.typ) + ":_files"
+		}
+		fmt.Fprintf(&b, " \\\n\t\t%s", quoteSh(spec))
+	}
+	b.WriteString("\n}\n\n")
+	fmt.Fprintf(&b, "if [ \"$funcstack[1]\" = %[1]s ]; then\n\t%[1]s \"$@\"\nelse\n\tcompdef %[1]s %[2]s\nfi\n",
+		fn, quoteSh(name))
+
+	return b.String()
+}
+
+func fishCompletion(name string, flags []completionFlag) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", name)
+	for _, f := range flags {
+		fmt.Fprintf(&b, "complete -c %s -o %s", quoteFish(name), quoteFish(f.name))
+		if f.usage != "" {
+			fmt.Fprintf(&b, " -d %s", quoteFish(f.usage))
+		}
+		switch {
+		case f.bool:
+		case len(f.values) > 0:
+			fmt.Fprintf(&b, " -x -a %s", quoteFish(strings.Join(f.values, " ")))
+		default:
+			b.WriteString(" -r")
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// quoteSh single quotes s for bash and zsh
+func quoteSh(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quoteFish single quotes s for fish, where backslashes and quotes are escaped inside the quotes
+func quoteFish(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// zshEscape escapes the characters that end a part of an _arguments spec
+func zshEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, ":", `\:`, "(", `\(`, ")", `\)`).Replace(s)
+}
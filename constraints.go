@@ -0,0 +1,196 @@
+package ruadan
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrConstraint is wrapped by the FieldError of each field that breaks a constraint on another field
+var ErrConstraint = errors.New("constraint not met")
+
+// ConstraintError is returned when fields break the constraints set on them with the lt_field:, lte_field:,
+// gt_field:, gte_field:, eq_field:, ne_field: or requires: tags. Constraints are checked once every layer is merged
+// and every broken one is listed
+type ConstraintError struct {
+	Fields []*FieldError
+}
+
+// Error lists the broken constraints
+func (e *ConstraintError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Error()
+	}
+
+	return "constraints not met: " + strings.Join(fields, ", ")
+}
+
+// Is reports whether any of the broken constraints matches target, so errors.Is(err, ErrConstraint) holds
+func (e *ConstraintError) Is(target error) bool {
+	return fieldsIs(e.Fields, target)
+}
+
+// As finds the first of the broken constraints that matches target, such as a *FieldError
+func (e *ConstraintError) As(target interface{}) bool {
+	return fieldsAs(e.Fields, target)
+}
+
+// constraintErr wraps ErrConstraint with a message saying what the constraint is
+type constraintErr string
+
+func (e constraintErr) Error() string {
+	return string(e)
+}
+
+func (e constraintErr) Unwrap() error {
+	return ErrConstraint
+}
+
+// fieldComparisons are the tags comparing a field to a sibling, with what they expect of the comparison and how that
+// reads in an error
+var fieldComparisons = []struct {
+	tag  string
+	ok   func(cmp int) bool
+	desc string
+}{
+	{"lt_field", func(cmp int) bool { return cmp < 0 }, "less than"},
+	{"lte_field", func(cmp int) bool { return cmp <= 0 }, "at most"},
+	{"gt_field", func(cmp int) bool { return cmp > 0 }, "greater than"},
+	{"gte_field", func(cmp int) bool { return cmp >= 0 }, "at least"},
+	{"eq_field", func(cmp int) bool { return cmp == 0 }, "equal to"},
+	{"ne_field", func(cmp int) bool { return cmp != 0 }, "different from"},
+}
+
+// checkConstraints returns a ConstraintError listing every field that breaks a constraint on a sibling field.
+// Comparisons are only made once both fields are set, and a field tagged `requires:"A,B"` needs A and B set whenever
+// it is set itself
+func checkConstraints(metas []fieldMeta) error {
+	broken := []*FieldError{}
+	for _, meta := range metas {
+		for _, c := range fieldComparisons {
+			name := meta.Tags.Get(c.tag)
+			if name == "" {
+				continue
+			}
+
+			other, ok := findSibling(metas, meta, name)
+			if !ok {
+				return newFieldError(meta, "", fmt.Errorf("%s field %s not found", c.tag, name))
+			}
+
+			// a field left at its zero value is unset, so there is nothing to compare yet
+			if isZero(meta.Field) || isZero(other.Field) {
+				continue
+			}
+
+			cmp, err := compareFields(meta.Field, other.Field)
+			if err != nil {
+				return newFieldError(meta, "", fmt.Errorf("%s %s: %v", c.tag, name, err))
+			}
+
+			if !c.ok(cmp) {
+				msg := fmt.Sprintf("must be %s %s (%s), got %s", c.desc, name, formatMeta(other), formatMeta(meta))
+				broken = append(broken, newFieldError(meta, formatMeta(meta), constraintErr(msg)))
+			}
+		}
+
+		requires := meta.Tags.Get("requires")
+		if requires == "" || isZero(meta.Field) {
+			continue
+		}
+
+		for _, name := range strings.Split(requires, ",") {
+			name = strings.TrimSpace(name)
+			other, ok := findSibling(metas, meta, name)
+			if !ok {
+				return newFieldError(meta, "", fmt.Errorf("required field %s not found", name))
+			}
+
+			if isZero(other.Field) {
+				msg := "requires " + name + " (flag: " + tagCLI(other) + " or env: " + tagENV(other) + ") to be set"
+				broken = append(broken, newFieldError(meta, formatMeta(meta), constraintErr(msg)))
+			}
+		}
+	}
+
+	if len(broken) > 0 {
+		return &ConstraintError{Fields: broken}
+	}
+
+	return nil
+}
+
+// compareFields compares two numbers or two strings, returning -1, 0 or 1. Other kinds can only be compared when
+// they have the same type, and are then either equal, 0, or not, 1
+func compareFields(a, b reflect.Value) (int, error) {
+	for a.Kind() == reflect.Ptr && !a.IsNil() {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Ptr && !b.IsNil() {
+		b = b.Elem()
+	}
+
+	switch {
+	case isInt(a) && isInt(b):
+		return sign(a.Int() < b.Int(), a.Int() > b.Int()), nil
+	case isUint(a) && isUint(b):
+		return sign(a.Uint() < b.Uint(), a.Uint() > b.Uint()), nil
+	case isNumber(a) && isNumber(b):
+		return sign(toFloat(a) < toFloat(b), toFloat(a) > toFloat(b)), nil
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return strings.Compare(a.String(), b.String()), nil
+	case a.Type() == b.Type():
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			return 0, nil
+		}
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("can't compare a %s with a %s", a.Type(), b.Type())
+	}
+}
+
+func sign(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(v reflect.Value) bool {
+	return isInt(v) || isUint(v) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func toFloat(v reflect.Value) float64 {
+	switch {
+	case isInt(v):
+		return float64(v.Int())
+	case isUint(v):
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
@@ -0,0 +1,67 @@
+package ruadan
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// countValue is the flag.Value of an int field tagged flagtype:"count", which counts how many times its flag is given
+// so -v -v -v sets it to 3. A number sets it outright, which is how env, files and defaults set it
+type countValue struct {
+	field reflect.Value
+	// counting is set once the flag has been given, so the first -v counts from 0 rather than from the value of a
+	// lower layer
+	counting bool
+}
+
+func (c *countValue) Set(value string) error {
+	if b, err := strconv.ParseBool(value); err == nil && value != "0" && value != "1" {
+		if !b {
+			c.field.SetInt(0)
+			c.counting = false
+			return nil
+		}
+		if !c.counting {
+			c.field.SetInt(0)
+			c.counting = true
+		}
+		return parseValue(strconv.FormatInt(c.field.Int()+1, 10), c.field)
+	}
+
+	c.counting = false
+	return parseValue(value, c.field)
+}
+
+// String prints 0 for the zero countValue, the same as a zero count, so flag.PrintDefaults leaves a zero default out
+func (c *countValue) String() string {
+	if c == nil || !c.field.IsValid() {
+		return "0"
+	}
+
+	return fmt.Sprint(c.field.Interface())
+}
+
+// typeName is empty as the flag takes no value on the cli, the same as a bool
+func (c *countValue) typeName() string {
+	return ""
+}
+
+// IsBoolFlag lets the flag be given bare, each -v adding one
+func (c *countValue) IsBoolFlag() bool {
+	return true
+}
+
+// flagType checks the flagtype: tag of a field, which only takes count and only on int fields
+func flagType(field reflect.Value, tags reflect.StructTag) error {
+	switch kind, ok := tags.Lookup("flagtype"); {
+	case !ok:
+		return nil
+	case kind != "count":
+		return fmt.Errorf("unknown flagtype: %q, expected count", kind)
+	case !isInt(field) || field.Type() == durationType:
+		return fmt.Errorf("flagtype: count needs an int field, not %s", field.Type())
+	default:
+		return nil
+	}
+}
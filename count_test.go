@@ -0,0 +1,64 @@
+package ruadan
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type countConfig struct {
+	Verbose int `envconfig:"VERBOSE" flagtype:"count" short:"v"`
+}
+
+func TestCountFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want int
+	}{
+		{name: "unset", want: 0},
+		{name: "once", args: []string{"-v"}, want: 1},
+		{name: "three times", args: []string{"-v", "-v", "-VERBOSE"}, want: 3},
+		{name: "env", env: map[string]string{"VERBOSE": "2"}, want: 2},
+		{name: "cli starts from zero", env: map[string]string{"VERBOSE": "2"}, args: []string{"-v"}, want: 1},
+		{name: "number", args: []string{"-VERBOSE=5"}, want: 5},
+		{name: "reset", args: []string{"-v", "-v", "-VERBOSE=0"}, want: 0},
+		{name: "false", args: []string{"-v", "-VERBOSE=false"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg countConfig
+			if err := testLoad(&cfg, tt.env, tt.args, nil); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Verbose != tt.want {
+				t.Errorf("loaded %d, want %d", cfg.Verbose, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountFlagHasNoNegation(t *testing.T) {
+	var cfg countConfig
+	err := testLoad(&cfg, nil, []string{"-v", "-no-VERBOSE=false"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "-no-VERBOSE") {
+		t.Errorf("expected -no-VERBOSE to be undefined, got %v with %+v", err, cfg)
+	}
+
+	var out bytes.Buffer
+	_ = testLoad(&cfg, nil, []string{"-h"}, nil, WithOutput(&out))
+	if strings.Contains(out.String(), "[no-]") || !strings.Contains(out.String(), "-v, -VERBOSE") {
+		t.Errorf("usage shows a -no- flag for the count:\n%s", out.String())
+	}
+}
+
+func TestCountFlagType(t *testing.T) {
+	var cfg struct {
+		Name string `flagtype:"count"`
+	}
+	if err := testLoad(&cfg, nil, nil, nil); err == nil {
+		t.Error("expected flagtype:\"count\" on a string to fail")
+	}
+}
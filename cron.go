@@ -0,0 +1,204 @@
+package ruadan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSpec is a cron expression field type. Both the standard 5 field form (minute hour day-of-month month
+// day-of-week) and the 6 field form with a leading seconds field are accepted, along with the @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly macros. Fields support *, ?, lists, ranges, steps and the
+// JAN-DEC and SUN-SAT names
+type CronSpec struct {
+	raw    string
+	fields [6]uint64
+	// domStar and dowStar record an unrestricted day field, which changes how the two day fields are combined
+	domStar bool
+	dowStar bool
+}
+
+type cronBounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	cronSecond = cronBounds{min: 0, max: 59}
+	cronMinute = cronBounds{min: 0, max: 59}
+	cronHour   = cronBounds{min: 0, max: 23}
+	cronDom    = cronBounds{min: 1, max: 31}
+	cronMonth  = cronBounds{min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronBounds{min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+
+	cronMacros = map[string]string{
+		"@yearly":   "0 0 0 1 1 *",
+		"@annually": "0 0 0 1 1 *",
+		"@monthly":  "0 0 0 1 * *",
+		"@weekly":   "0 0 0 * * 0",
+		"@daily":    "0 0 0 * * *",
+		"@midnight": "0 0 0 * * *",
+		"@hourly":   "0 0 * * * *",
+	}
+)
+
+// ParseCronSpec parses a 5 or 6 field cron expression, or one of the supported macros
+func ParseCronSpec(s string) (CronSpec, error) {
+	spec := CronSpec{raw: strings.TrimSpace(s)}
+	expr := spec.raw
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return CronSpec{}, fmt.Errorf("invalid cron expression %q: expected 5 or 6 fields, found %d", s, len(fields))
+	}
+
+	for i, bounds := range []cronBounds{cronSecond, cronMinute, cronHour, cronDom, cronMonth, cronDow} {
+		bits, err := parseCronField(fields[i], bounds)
+		if err != nil {
+			return CronSpec{}, fmt.Errorf("invalid cron expression %q: %v", s, err)
+		}
+		spec.fields[i] = bits
+	}
+
+	// 7 is another way of writing sunday
+	if spec.fields[5]&(1<<7) != 0 {
+		spec.fields[5] = spec.fields[5]&^(1<<7) | 1
+	}
+	// as in cron, a day field starting with *, such as */2, counts as unrestricted even though it skips days
+	spec.domStar = strings.HasPrefix(fields[3], "*") || fields[3] == "?"
+	spec.dowStar = strings.HasPrefix(fields[5], "*") || fields[5] == "?"
+
+	return spec, nil
+}
+
+func parseCronField(field string, bounds cronBounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := uint(1)
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step, part = uint(n), part[:i]
+		}
+
+		var start, end uint
+		switch {
+		case part == "*" || part == "?":
+			start, end = bounds.min, bounds.max
+		case strings.Contains(part, "-"):
+			parts := strings.SplitN(part, "-", 2)
+			var err error
+			if start, err = cronValue(parts[0], bounds); err != nil {
+				return 0, err
+			}
+			if end, err = cronValue(parts[1], bounds); err != nil {
+				return 0, err
+			}
+			if end < start {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			v, err := cronValue(part, bounds)
+			if err != nil {
+				return 0, err
+			}
+			start, end = v, v
+			if step > 1 {
+				end = bounds.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+func cronValue(s string, bounds cronBounds) (uint, error) {
+	if v, ok := bounds.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(v) < bounds.min || uint(v) > bounds.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, bounds.min, bounds.max)
+	}
+
+	return uint(v), nil
+}
+
+// Set parses the value as a cron expression, used when reading from env or cli
+func (c *CronSpec) Set(value string) error {
+	parsed, err := ParseCronSpec(value)
+	if err != nil {
+		return err
+	}
+
+	*c = parsed
+	return nil
+}
+
+// String returns the expression as it was set
+func (c *CronSpec) String() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.raw
+}
+
+// Next returns the first time after t that matches the spec, or the zero time if nothing matches within five years
+func (c CronSpec) Next(t time.Time) time.Time {
+	if c.raw == "" {
+		return time.Time{}
+	}
+
+	t = t.Add(time.Second - time.Duration(t.Nanosecond()))
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case c.fields[4]&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.fields[2]&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.fields[1]&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+		case c.fields[0]&(1<<uint(t.Second())) == 0:
+			t = t.Add(time.Second)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows cron's rule that when both day fields are restricted a day matching either is enough
+func (c CronSpec) dayMatches(t time.Time) bool {
+	dom := c.fields[3]&(1<<uint(t.Day())) != 0
+	dow := c.fields[5]&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+
+	return dom || dow
+}
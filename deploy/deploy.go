@@ -0,0 +1,91 @@
+// Package deploy generates the env var declarations of service managers from a ruadan config struct, so daemon and
+// desktop deployments set the same env vars the config reads. Fields come from ruadan.Describe:
+//
+//	fields, err := ruadan.Describe(&cfg, ruadan.WithPrefix("MYAPP"))
+//	err = deploy.Launchd(os.Stdout, fields)
+package deploy
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bit-cmdr/ruadan"
+)
+
+// Launchd writes the EnvironmentVariables key of a launchd plist, with every field set to its default. Required fields
+// without a default and secrets are left empty with a comment, ready to be filled in
+func Launchd(w io.Writer, fields []ruadan.FieldInfo) error {
+	var b strings.Builder
+	b.WriteString("<key>EnvironmentVariables</key>\n<dict>\n")
+	for _, f := range fields {
+		if n := note(f); n != "" {
+			fmt.Fprintf(&b, "\t<!-- %s -->\n", n)
+		}
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<string>%s</string>\n", escapeXML(f.Env), escapeXML(value(f)))
+	}
+	b.WriteString("</dict>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WindowsService writes a PowerShell snippet that installs the service called name running binPath, then sets its
+// Environment registry value, which the service control manager passes to the service as env vars. A binPath with
+// spaces is wrapped in double quotes so it isn't split. Required fields without a default and secrets are left empty
+// with a comment, ready to be filled in
+func WindowsService(w io.Writer, name, binPath string, fields []ruadan.FieldInfo) error {
+	if strings.ContainsAny(binPath, " \t") && !strings.HasPrefix(binPath, `"`) {
+		binPath = `"` + binPath + `"`
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "New-Service -Name %s -BinaryPathName %s -StartupType Automatic\n", quotePS(name), quotePS(binPath))
+	fmt.Fprintf(&b, "Set-ItemProperty -Path %s -Name Environment -Type MultiString -Value @(\n",
+		quotePS(`HKLM:\SYSTEM\CurrentControlSet\Services\`+name))
+	for _, f := range fields {
+		if n := note(f); n != "" {
+			fmt.Fprintf(&b, "    # %s\n", n)
+		}
+		fmt.Fprintf(&b, "    %s\n", quotePS(f.Env+"="+value(f)))
+	}
+	b.WriteString(")\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// value is the value written for a field, its default unless it is a secret, which is never written to a file
+func value(f ruadan.FieldInfo) string {
+	if f.Secret {
+		return ""
+	}
+
+	return f.Default
+}
+
+// note is the comment written above a field that has to be filled in, if any
+func note(f ruadan.FieldInfo) string {
+	switch {
+	case f.Secret && f.Required:
+		return "required, secret"
+	case f.Secret:
+		return "secret"
+	case f.Required && f.Default == "":
+		return "required"
+	default:
+		return ""
+	}
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// quotePS quotes s as a PowerShell single quoted string, where a quote is escaped by doubling it
+func quotePS(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,113 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"strings"
+)
+
+// renamedValue is the flag.Value of the old flag of a field tagged deprecated:, which sets the same value as the flag
+// that replaced it
+type renamedValue struct {
+	flag.Value
+	// name is the flag that replaced it
+	name string
+}
+
+func (r *renamedValue) String() string {
+	// a zero value prints empty, so flag.PrintDefaults leaves it out of the default of the alias
+	if r == nil || r.Value == nil || zeroDefault(r.Value.String()) {
+		return ""
+	}
+
+	return r.Value.String()
+}
+
+func (r *renamedValue) aliasOf() string {
+	return r.name
+}
+
+func (r *renamedValue) typeName() string {
+	return valueTypeName(r.Value)
+}
+
+// IsBoolFlag lets the old flag of a bool be given bare, the same as the flag that replaced it
+func (r *renamedValue) IsBoolFlag() bool {
+	b, ok := r.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// renamedMetas returns a meta for each old name in the deprecated: tag of meta, e.g. `deprecated:"timeout"` or
+// `deprecated:"timeout,wait"`, named as the field was before it was renamed. The prefix and parent structs are kept,
+// so the old env and flag are built the same way as the new ones
+func renamedMetas(meta fieldMeta) []fieldMeta {
+	tag := meta.Tags.Get("deprecated")
+	if tag == "" {
+		return nil
+	}
+
+	olds := []fieldMeta{}
+	for _, name := range strings.Split(tag, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+
+		old := meta
+		old.AltENV, old.AltCLI, old.AltJSON = strings.ToUpper(name), name, ""
+		old.env, old.cli = "", ""
+		olds = append(olds, old)
+	}
+
+	return olds
+}
+
+// registerRenamed adds the old flag of every field tagged deprecated:, unless the name is already a flag
+func registerRenamed(fs *flag.FlagSet, metas []fieldMeta) {
+	for _, meta := range metas {
+		f := fs.Lookup(tagCLI(meta))
+		if f == nil {
+			continue
+		}
+
+		for _, old := range renamedMetas(meta) {
+			if name := tagCLI(old); fs.Lookup(name) == nil {
+				fs.Var(&renamedValue{Value: f.Value, name: f.Name}, name, f.Usage)
+			}
+		}
+	}
+}
+
+// checkRenamed emits a warn Diagnostic for every old flag or env of a field tagged deprecated: that is still set,
+// naming the one to use instead
+func checkRenamed(ctx context.Context, fs *flag.FlagSet, metas []fieldMeta, srcs sources, report func(Diagnostic)) {
+	if report == nil {
+		return
+	}
+
+	given := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	for _, meta := range metas {
+		for _, old := range renamedMetas(meta) {
+			if name := tagCLI(old); given[name] {
+				if r, ok := fs.Lookup(name).Value.(*renamedValue); ok && r.name == tagCLI(meta) {
+					report(Diagnostic{
+						Level:   LevelWarn,
+						Field:   meta.Name,
+						Message: "flag -" + name + " is deprecated, use -" + tagCLI(meta),
+					})
+				}
+			}
+
+			if key := tagENV(old); key != tagENV(meta) {
+				if src, _, ok := srcs.lookup(ctx, key); ok {
+					report(Diagnostic{
+						Level:   LevelWarn,
+						Field:   meta.Name,
+						Message: sourceLabel(src, key, "") + " is deprecated, use " + tagENV(meta),
+					})
+				}
+			}
+		}
+	}
+}
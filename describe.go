@@ -0,0 +1,82 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"strings"
+)
+
+// FieldInfo describes how a field of a config struct is set, for generating docs and deployment files
+type FieldInfo struct {
+	// Field is the dotted path of the field, e.g. Server.Port
+	Field string
+	// Type is the Go type of the field, e.g. int or time.Duration
+	Type string
+	Env  string
+	Flag string
+	// Short is the single-letter alias of the flag set with a short: tag, if any
+	Short string
+	// Default is Redacted for a Secret field with a default
+	Default  string
+	Required bool
+	Secret   bool
+	Usage    string
+}
+
+// Describe returns a FieldInfo for every field of cfg, which must be a struct pointer, without reading any cli, env or
+// file. Defaults are the values cfg holds or its default: tags, formatted the same way as in the usage. WithPrefix is
+// honored and other options are ignored
+func Describe(cfg interface{}, options ...LoadOptions) ([]FieldInfo, error) {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	opt := newLoadOption(options...)
+	metas, err := reflectConfig(opt.prefix, cloneStruct(v).Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	// register the fields on a scratch flag set that only reads defaults, so defaults are formatted by their flags
+	defaults := &LoadOption{precedence: []Layer{Default}}
+	fs := flag.NewFlagSet("describe", flag.ContinueOnError)
+	infos := make([]FieldInfo, 0, len(metas))
+	for _, meta := range metas {
+		_ = parseMeta(fs, meta, lookupMeta(context.Background(), defaults, meta), false)
+
+		info := FieldInfo{
+			Field:    fieldPath(meta),
+			Type:     meta.Field.Type().String(),
+			Env:      tagENV(meta),
+			Flag:     tagCLI(meta),
+			Short:    meta.Tags.Get("short"),
+			Required: tagBool(meta.Tags, "required"),
+			Secret:   isSecret(meta),
+			Usage:    meta.DescCLI,
+		}
+		if f := fs.Lookup(info.Flag); f != nil && !zeroDefault(f.DefValue) {
+			info.Default = redact(meta, f.DefValue)
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// fieldPath returns the dotted path of a field from the top level struct
+func fieldPath(meta fieldMeta) string {
+	if len(meta.Parents) == 0 {
+		return meta.Name
+	}
+
+	var b strings.Builder
+	for _, p := range meta.Parents {
+		b.WriteString(p.Name)
+		b.WriteByte('.')
+	}
+	b.WriteString(meta.Name)
+
+	return b.String()
+}
@@ -0,0 +1,109 @@
+package ruadan
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+)
+
+// DiffConfigFlag is the cli flag, added along with the config flag, that prints how the config would change with
+// another config file instead of running
+const DiffConfigFlag = "diff-config"
+
+// ErrDiffConfig is returned by Load after printing the diff asked for with the diff-config flag, unless the error
+// handling is flag.ExitOnError, in which case the process exits with status 0 the same as for -h
+var ErrDiffConfig = errors.New("config diff printed")
+
+// FieldDiff is a field whose value would change, with its values written the same way they are read from env. The
+// values of fields tagged secret: are Redacted, the change is still reported
+type FieldDiff struct {
+	Field string
+	Env   string
+	Old   string
+	New   string
+}
+
+// String formats the FieldDiff as a single line
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s (%s): %q -> %q", d.Field, d.Env, d.Old, d.New)
+}
+
+// DiffFile loads the struct of the last call to Load again with the file at path in place of the config file, or
+// above every other file when there is no config file, and returns the fields whose values would change. The cli,
+// sources and the rest of the files are read the same as for Load, and nothing is applied to the loaded struct
+func (l *Loader) DiffFile(ctx context.Context, path string) ([]FieldDiff, error) {
+	l.mu.Lock()
+	current, initial := l.lock, l.initial
+	l.mu.Unlock()
+	if current == nil {
+		return nil, errors.New("nothing has been loaded to diff against")
+	}
+
+	opt := *l.opt
+	opt.candidate = path
+	opt.handling = flag.ContinueOnError
+	opt.output = ioutil.Discard
+	opt.diagnostics = func(Diagnostic) {}
+
+	cfg := reflect.New(initial.Type().Elem())
+	cfg.Elem().Set(initial.Elem())
+	next := &Loader{opt: &opt}
+	if err := next.Load(ctx, cfg.Interface()); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return diffLocks(current, next.Lock()), nil
+}
+
+// diffLocks compares the values of two loads of the same struct, sorted by field
+func diffLocks(current, next *Lock) []FieldDiff {
+	diffs := []FieldDiff{}
+	for field, env := range next.envs {
+		if was, ok := current.Values[env]; !ok || was != next.Values[env] {
+			diffs = append(diffs, FieldDiff{Field: field, Env: env, Old: was, New: next.Values[env]})
+		}
+	}
+	for field, env := range current.envs {
+		if _, ok := next.Values[env]; !ok {
+			diffs = append(diffs, FieldDiff{Field: field, Env: env, Old: current.Values[env]})
+		}
+	}
+
+	for i, d := range diffs {
+		if current.secrets[d.Env] || next.secrets[d.Env] {
+			diffs[i].Old, diffs[i].New = redactValue(d.Old), redactValue(d.New)
+		}
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+// printDiff writes the diff asked for with the diff-config flag to the output of fs
+func (l *Loader) printDiff(ctx context.Context, fs *flag.FlagSet, path string) error {
+	diffs, err := l.DiffFile(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	w := fs.Output()
+	if len(diffs) == 0 {
+		fmt.Fprintf(w, "no config changes with %s\n", path)
+	} else {
+		fmt.Fprintf(w, "config changes with %s:\n", path)
+		for _, d := range diffs {
+			fmt.Fprintf(w, "  %s\n", d)
+		}
+	}
+
+	if l.opt.handling == flag.ExitOnError {
+		os.Exit(0)
+	}
+
+	return ErrDiffConfig
+}
@@ -0,0 +1,104 @@
+// Package docs generates the documentation of a ruadan config struct, listing the env var, flag, type, default and
+// description of every field, so ops docs can be regenerated in a build and never drift from the code. Fields come
+// from ruadan.Describe:
+//
+//	fields, err := ruadan.Describe(&cfg, ruadan.WithPrefix("MYAPP"))
+//	err = docs.Markdown(os.Stdout, fields)
+package docs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bit-cmdr/ruadan"
+)
+
+// Markdown writes a table with a row for every field
+func Markdown(w io.Writer, fields []ruadan.FieldInfo) error {
+	var b strings.Builder
+	b.WriteString("| Env | Flag | Type | Default | Description |\n")
+	b.WriteString("| --- | --- | --- | --- | --- |\n")
+	for _, f := range fields {
+		def := ""
+		if f.Default != "" {
+			def = "`" + escapeMarkdown(f.Default) + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | `-%s` | %s | %s | %s |\n",
+			escapeMarkdown(f.Env), escapeMarkdown(f.Flag), escapeMarkdown(f.Type), def, escapeMarkdown(description(f)))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Man writes a man page in roff for the program called name, in section 1, with the flags under OPTIONS and the env
+// vars under ENVIRONMENT
+func Man(w io.Writer, name string, fields []ruadan.FieldInfo) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, ".TH %s 1\n", escapeRoff(strings.ToUpper(name)))
+	fmt.Fprintf(&b, ".SH NAME\n%s\n", escapeRoff(name))
+	b.WriteString(".SH OPTIONS\n")
+	for _, f := range fields {
+		fmt.Fprintf(&b, ".TP\n.B \\-%s \\fI%s\\fR\n%s\n", escapeRoff(f.Flag), escapeRoff(f.Type), escapeRoff(description(f)))
+	}
+	b.WriteString(".SH ENVIRONMENT\n")
+	for _, f := range fields {
+		fmt.Fprintf(&b, ".TP\n.B %s\n", escapeRoff(f.Env))
+		if f.Default != "" {
+			fmt.Fprintf(&b, "Defaults to %s. ", escapeRoff(f.Default))
+		}
+		fmt.Fprintf(&b, "Same as \\fB\\-%s\\fR.\n", escapeRoff(f.Flag))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Text writes a plain text table with a line for every field, aligned in columns
+func Text(w io.Writer, fields []ruadan.FieldInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENV\tFLAG\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, f := range fields {
+		fmt.Fprintf(tw, "%s\t-%s\t%s\t%s\t%s\n", f.Env, f.Flag, f.Type, f.Default, description(f))
+	}
+
+	return tw.Flush()
+}
+
+// description is the clidesc: of a field, or its path when it has none, followed by whether it is required or secret
+func description(f ruadan.FieldInfo) string {
+	desc := f.Usage
+	if desc == "" {
+		desc = f.Field
+	}
+
+	notes := []string{}
+	if f.Required && f.Default == "" {
+		notes = append(notes, "required")
+	}
+	if f.Secret {
+		notes = append(notes, "secret")
+	}
+	if len(notes) > 0 {
+		desc += " (" + strings.Join(notes, ", ") + ")"
+	}
+
+	return desc
+}
+
+// escapeMarkdown escapes the characters that would break out of a table cell or code span
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer("|", `\|`, "`", "'", "\n", " ").Replace(s)
+}
+
+// escapeRoff escapes backslashes and hyphens, and a leading dot or quote that roff would read as a request
+func escapeRoff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`, "\n", " ").Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+
+	return s
+}
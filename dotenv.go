@@ -0,0 +1,203 @@
+package ruadan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+)
+
+// WithDotEnv reads the .env file at path into the env layer, just below the real env, so variables already set in
+// the environment win over the file. Each line is a KEY=value pair, optionally starting with export. Values can be
+// single quoted to be read as-is, or double quoted to allow \n, \t, \" and \\ escapes, and either can span several
+// lines. Lines starting with # and anything after a # that follows a space in an unquoted value are comments. A
+// missing file is skipped, so the same binary runs with or without one
+func WithDotEnv(path string) LoadOptions {
+	return func(o *LoadOption) { o.dotenv = append(o.dotenv, path) }
+}
+
+// withDotEnv returns srcs with the values of every .env file inserted after EnvSource, or at the end when srcs
+// has no EnvSource. Later files win over earlier ones
+func (srcs sources) withDotEnv(fsys fs.FS, paths []string) (sources, error) {
+	if len(paths) == 0 {
+		return srcs, nil
+	}
+
+	values := MapSource{}
+	for _, path := range paths {
+		vals, err := readDotEnv(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		for k, v := range vals {
+			values[k] = v
+		}
+	}
+
+	at := len(srcs)
+	for i, s := range srcs {
+		if _, ok := s.(EnvSource); ok {
+			at = i + 1
+			break
+		}
+	}
+
+	out := make(sources, 0, len(srcs)+1)
+	out = append(out, srcs[:at]...)
+	out = append(out, values)
+	return append(out, srcs[at:]...), nil
+}
+
+func readDotEnv(fsys fs.FS, path string) (map[string]string, error) {
+	r, err := openFile(fsys, path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	values, err := parseDotEnv(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return values, nil
+}
+
+// parseDotEnv reads KEY=value pairs from a .env file
+func parseDotEnv(r io.Reader) (map[string]string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	line := 1
+	for len(s) > 0 {
+		start := line
+		var raw string
+		raw, s = cutLine(s)
+		line++
+
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		trimmed = strings.TrimPrefix(trimmed, "export ")
+		eq := strings.IndexByte(trimmed, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("dotenv: line %d: expected KEY=value", start)
+		}
+
+		key := strings.TrimSpace(trimmed[:eq])
+		val := strings.TrimLeft(trimmed[eq+1:], " \t")
+		if val == "" || (val[0] != '"' && val[0] != '\'') {
+			values[key] = stripDotEnvComment(val)
+			continue
+		}
+
+		// a quoted value runs until its closing quote, which may be on a later line
+		quote := val[0]
+		val = val[1:]
+		var b strings.Builder
+		for {
+			end := closingQuote(val, quote)
+			if end >= 0 {
+				b.WriteString(val[:end])
+				rest := strings.TrimSpace(val[end+1:])
+				if rest != "" && !strings.HasPrefix(rest, "#") {
+					return nil, fmt.Errorf("dotenv: line %d: unexpected text after closing quote", line-1)
+				}
+				break
+			}
+
+			if len(s) == 0 {
+				return nil, fmt.Errorf("dotenv: line %d: unterminated quoted value", start)
+			}
+
+			b.WriteString(val)
+			b.WriteByte('\n')
+			val, s = cutLine(s)
+			line++
+		}
+
+		if quote == '"' {
+			values[key] = unescapeDotEnv(b.String())
+		} else {
+			values[key] = b.String()
+		}
+	}
+
+	return values, nil
+}
+
+func cutLine(s string) (string, string) {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+
+	return s, ""
+}
+
+// closingQuote returns the index of the quote that ends the value, skipping escaped quotes in double quoted values
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+
+	return -1
+}
+
+func unescapeDotEnv(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\', '$':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// stripDotEnvComment drops a # comment that follows whitespace in an unquoted value
+func stripDotEnvComment(s string) string {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '#' && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,156 @@
+package ruadan
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// enabledField is the name of the bool field that switches a nested struct on and off
+const enabledField = "Enabled"
+
+// applyEnabled zeroes the fields of every nested struct that is switched off, along with any structs nested in it,
+// and returns the metas of the fields that are left to resolve and validate. A struct is switched off when its Enabled
+// bool field is false, or when it is a branch not picked by a selects: field. The Enabled and selects: fields
+// themselves are always kept. A selects: field naming a branch that doesn't exist is returned as a FieldError
+func applyEnabled(metas []fieldMeta) ([]fieldMeta, error) {
+	disabled := [][]fieldMeta{}
+	errs := []error{}
+	for _, meta := range metas {
+		if meta.Name == enabledField && len(meta.Parents) > 0 && meta.Field.Kind() == reflect.Bool &&
+			!meta.Field.Bool() {
+			disabled = append(disabled, meta.Parents)
+		}
+
+		branches, ok := meta.Tags.Lookup("selects")
+		if !ok || meta.Field.Kind() != reflect.String {
+			continue
+		}
+
+		inactive, err := inactiveBranches(meta, branches)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		disabled = append(disabled, inactive...)
+	}
+
+	if len(disabled) == 0 {
+		return metas, joinErrors(errs)
+	}
+
+	active := make([]fieldMeta, 0, len(metas))
+	for _, meta := range metas {
+		if meta.Name == enabledField || !insideAny(meta, disabled) {
+			active = append(active, meta)
+			continue
+		}
+
+		meta.Field.Set(reflect.Zero(meta.Field.Type()))
+	}
+
+	return active, joinErrors(errs)
+}
+
+// inactiveBranches returns the paths of the sibling structs listed by a selects: field other than the one its value
+// names, matched case-insensitively. Every branch is inactive while the field is empty
+func inactiveBranches(meta fieldMeta, branches string) ([][]fieldMeta, error) {
+	selected := meta.Field.String()
+	found := selected == ""
+	names := splitBranches(branches)
+	inactive := make([][]fieldMeta, 0, len(names))
+	for _, name := range names {
+		if strings.EqualFold(name, selected) {
+			found = true
+			continue
+		}
+
+		path := append(meta.Parents[:len(meta.Parents):len(meta.Parents)], fieldMeta{Name: name})
+		inactive = append(inactive, path)
+	}
+
+	if !found {
+		return inactive, newFieldError(meta, selected, fmt.Errorf("must be one of %s", strings.Join(names, ", ")))
+	}
+
+	return inactive, nil
+}
+
+func splitBranches(branches string) []string {
+	names := strings.Split(branches, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+
+	return names
+}
+
+// Selected returns a pointer to the branch picked by the selects: field of the struct s points to, e.g. &cfg.Storage,
+// or nil when the field is empty. The result can be switched on by type:
+//
+//	switch b := branch.(type) {
+//	case *S3Config:
+//	case *GCSConfig:
+//	}
+func Selected(s interface{}) (interface{}, error) {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		branches, ok := t.Field(i).Tag.Lookup("selects")
+		if !ok || v.Field(i).Kind() != reflect.String {
+			continue
+		}
+
+		selected := v.Field(i).String()
+		if selected == "" {
+			return nil, nil
+		}
+
+		for _, name := range splitBranches(branches) {
+			if !strings.EqualFold(name, selected) {
+				continue
+			}
+
+			branch := v.FieldByName(name)
+			switch {
+			case !branch.IsValid():
+				return nil, fmt.Errorf("%s has no branch %s", t, name)
+			case branch.Kind() == reflect.Ptr:
+				return branch.Interface(), nil
+			default:
+				return branch.Addr().Interface(), nil
+			}
+		}
+
+		return nil, fmt.Errorf("%s: %q is not one of %s", t.Field(i).Name, selected, branches)
+	}
+
+	return nil, fmt.Errorf("%s has no field tagged selects", t)
+}
+
+// insideAny reports whether meta is a field of one of the structs, or of a struct nested in one, given by the
+// parents of their fields
+func insideAny(meta fieldMeta, structs [][]fieldMeta) bool {
+	for _, parents := range structs {
+		if len(meta.Parents) < len(parents) {
+			continue
+		}
+
+		inside := true
+		for i := range parents {
+			if meta.Parents[i].Name != parents[i].Name {
+				inside = false
+				break
+			}
+		}
+		if inside {
+			return true
+		}
+	}
+
+	return false
+}
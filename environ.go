@@ -0,0 +1,27 @@
+package ruadan
+
+// Environ returns KEY=value pairs for the fields named, from the values of the last call to Load, to add to the env of
+// a child process, e.g. cmd.Env = append(os.Environ(), l.Environ("Database.URL", "LOG_LEVEL")...). Fields are named by
+// their Go path, with nested structs joined by dots, or by their env name, and the keys are their env names so the
+// child reads them the same way. Only the fields named are returned, names that don't match a field are skipped, and
+// nothing is returned if nothing has been loaded
+func (l *Loader) Environ(fields ...string) []string {
+	lock := l.Lock()
+	if lock == nil {
+		return nil
+	}
+
+	env := make([]string, 0, len(fields))
+	for _, name := range fields {
+		key, ok := lock.envs[name]
+		if !ok {
+			key = name
+		}
+
+		if val, ok := lock.Values[key]; ok {
+			env = append(env, key+"="+val)
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,267 @@
+package ruadan
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrUnknownKey is wrapped by the FieldError of each key in a config file that no field reads, returned in strict mode
+var ErrUnknownKey = errors.New("unknown key")
+
+// ErrUnsupportedType is wrapped by the FieldError of a field that is given a value but has a type that can't be set
+// from a string, such as a func or a chan
+var ErrUnsupportedType = errors.New("unsupported field type")
+
+// ErrChecksum is returned when the defaults file set with WithDefaultsFile doesn't match the sha256 it was shipped
+// with, which means it was changed after the build
+var ErrChecksum = errors.New("checksum mismatch")
+
+// ErrorKind is the category of a FieldError, so callers can branch on why a load failed without matching messages
+type ErrorKind int
+
+const (
+	// KindInvalidValue is a value that doesn't parse as the type of its field, or a tag that can't be used
+	KindInvalidValue ErrorKind = iota
+	// KindRequiredMissing is a required field left at its zero value, wrapping ErrRequiredMissing
+	KindRequiredMissing
+	// KindUnknownKey is a key in a config file that no field reads, wrapping ErrUnknownKey
+	KindUnknownKey
+	// KindUnsupportedType is a value given for a field that can't be set from a string, wrapping ErrUnsupportedType
+	KindUnsupportedType
+	// KindValidation is a field breaking a rule of its validate: tag, wrapping ErrValidation
+	KindValidation
+	// KindConstraint is a field breaking a constraint on another field, wrapping ErrConstraint
+	KindConstraint
+	// KindRemovedField is a field tagged `lifecycle:"removed"` that is still set, wrapping ErrRemovedField
+	KindRemovedField
+)
+
+var errorKinds = []struct {
+	err  error
+	kind ErrorKind
+	name string
+}{
+	{nil, KindInvalidValue, "invalid value"},
+	{ErrRequiredMissing, KindRequiredMissing, "required missing"},
+	{ErrUnknownKey, KindUnknownKey, "unknown key"},
+	{ErrUnsupportedType, KindUnsupportedType, "unsupported type"},
+	{ErrValidation, KindValidation, "validation"},
+	{ErrConstraint, KindConstraint, "constraint"},
+	{ErrRemovedField, KindRemovedField, "removed field"},
+}
+
+// String names the kind, e.g. required missing
+func (k ErrorKind) String() string {
+	if k < 0 || int(k) >= len(errorKinds) {
+		return "ErrorKind(" + strconv.Itoa(int(k)) + ")"
+	}
+
+	return errorKinds[k].name
+}
+
+// FieldError is returned when the value for a field can't be used, naming the field and the env and flag that set it.
+// A key in a config file that no field reads has no Field, and Key is the key in the file
+type FieldError struct {
+	Field string
+	Key   string
+	Flag  string
+	Value string
+	Err   error
+}
+
+// Error formats the error with the field and key it belongs to
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Key + ": " + e.Err.Error()
+	}
+
+	return e.Field + " (" + e.Key + "): " + e.Err.Error()
+}
+
+// Kind returns the category of the error from the sentinel error it wraps, KindInvalidValue if it wraps none of them
+func (e *FieldError) Kind() ErrorKind {
+	for _, k := range errorKinds[1:] {
+		if errors.Is(e.Err, k.err) {
+			return k.kind
+		}
+	}
+
+	return KindInvalidValue
+}
+
+// Unwrap returns the underlying error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+func newFieldError(meta fieldMeta, value string, err error) *FieldError {
+	return &FieldError{Field: meta.Name, Key: tagENV(meta), Flag: tagCLI(meta), Value: value, Err: err}
+}
+
+// LoadError is returned when loading a config finds more than one problem, such as several values that fail to parse
+// along with missing required fields, so they can all be fixed at once. A single problem is returned on its own. The
+// errors are listed by Unwrap for errors.Is and errors.As, which Is and As also do for Go versions before 1.20
+type LoadError struct {
+	Errors []error
+}
+
+// Error lists every problem, one per line
+func (e *LoadError) Error() string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(len(e.Errors)))
+	b.WriteString(" problems loading config:")
+	for _, err := range e.Errors {
+		b.WriteString("\n  ")
+		b.WriteString(err.Error())
+	}
+
+	return b.String()
+}
+
+// Unwrap returns every problem
+func (e *LoadError) Unwrap() []error {
+	return e.Errors
+}
+
+// Is reports whether any of the problems matches target
+func (e *LoadError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first problem that matches target
+func (e *LoadError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// appendErr adds err to errs if it isn't nil
+func appendErr(errs []error, err error) []error {
+	if err == nil {
+		return errs
+	}
+
+	return append(errs, err)
+}
+
+// joinErrors returns nil for no errors, the error itself for one and a LoadError for more, flattening any LoadError
+// in errs
+func joinErrors(errs []error) error {
+	flat := make([]error, 0, len(errs))
+	for _, err := range errs {
+		if le, ok := err.(*LoadError); ok {
+			flat = append(flat, le.Errors...)
+			continue
+		}
+		flat = appendErr(flat, err)
+	}
+
+	switch len(flat) {
+	case 0:
+		return nil
+	case 1:
+		return flat[0]
+	default:
+		return &LoadError{Errors: flat}
+	}
+}
+
+// ErrRequiredMissing is wrapped by the FieldError of each required field that was left at its zero value
+var ErrRequiredMissing = errors.New("required field is not set")
+
+// ErrRequired is the same error as ErrRequiredMissing
+//
+// Deprecated: use ErrRequiredMissing
+var ErrRequired = ErrRequiredMissing
+
+// RequiredError is returned by GetConfigFlagSet when fields tagged `required:"true"` are left at their zero value. It
+// lists every missing field along with the flag and env that would set it
+type RequiredError struct {
+	Fields []*FieldError
+}
+
+// Error lists the missing fields
+func (e *RequiredError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Field + " (flag: " + f.Flag + " or env: " + f.Key + ")"
+	}
+
+	return "required fields are not set: " + strings.Join(fields, ", ")
+}
+
+// Is reports whether any of the missing fields matches target, so errors.Is(err, ErrRequiredMissing) holds
+func (e *RequiredError) Is(target error) bool {
+	return fieldsIs(e.Fields, target)
+}
+
+// As finds the first missing field that matches target, such as a *FieldError
+func (e *RequiredError) As(target interface{}) bool {
+	return fieldsAs(e.Fields, target)
+}
+
+func fieldsIs(fields []*FieldError, target error) bool {
+	for _, f := range fields {
+		if errors.Is(f, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fieldsAs(fields []*FieldError, target interface{}) bool {
+	for _, f := range fields {
+		if errors.As(f, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkRequired returns a RequiredError if any field tagged `required:"true"` is still at its zero value
+func checkRequired(metas []fieldMeta) error {
+	missing := []*FieldError{}
+	for _, meta := range metas {
+		if !tagBool(meta.Tags, "required") || !isZero(meta.Field) {
+			continue
+		}
+
+		missing = append(missing, newFieldError(meta, "", ErrRequiredMissing))
+	}
+
+	if len(missing) > 0 {
+		return &RequiredError{Fields: missing}
+	}
+
+	return nil
+}
+
+func isZero(field reflect.Value) bool {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return true
+		}
+		field = field.Elem()
+	}
+
+	switch field.Kind() {
+	case reflect.Slice, reflect.Map:
+		return field.Len() == 0
+	default:
+		return field.IsZero()
+	}
+}
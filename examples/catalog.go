@@ -0,0 +1,769 @@
+package examples
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	rd "github.com/bit-cmdr/ruadan"
+)
+
+type basic struct {
+	TestString string `envconfig:"TEST_STRING"`
+	TestInt    int    `envcli:"testint"`
+	TestFloat  float64
+	Pass       bool
+}
+
+type defaults struct {
+	Port    int           `default:"8080"`
+	Timeout time.Duration `default:"5s"`
+	Hosts   []string      `default:"a,b"`
+}
+
+type platform struct {
+	DataDir string `default:"/var/lib/app" default_windows:"C:\\ProgramData\\app" default_darwin:"/usr/local/var/app"`
+}
+
+type build struct {
+	GoVersion string `buildinfo:"go.version"`
+	Commit    string `buildinfo:"vcs.no-such-setting" default:"unknown"`
+}
+
+type instance struct {
+	Instance string `auto:"hostname"`
+	PID      int    `auto:"pid"`
+}
+
+type server struct {
+	Host string
+	Port int
+}
+
+type nested struct {
+	Server server
+	Admin  server `noprefix:"true"`
+}
+
+type files struct {
+	Name  string `yaml:"name" json:"name" toml:"name"`
+	Port  int    `yaml:"port" json:"port" toml:"port"`
+	Hosts []string
+}
+
+type lists struct {
+	Hosts  []string
+	Ports  []int
+	Routes []route
+}
+
+type route struct {
+	Path    string `json:"path"`
+	Backend string `json:"backend"`
+}
+
+type legacy struct {
+	Name   string
+	Server struct {
+		Host  string
+		Port  int
+		Allow []string
+		TLS   struct {
+			Cert string `ini:"cert_file"`
+		}
+	}
+}
+
+type switched struct {
+	TLS struct {
+		Enabled bool
+		Cert    string `required:"true"`
+		Key     string `required:"true"`
+	}
+	Metrics struct {
+		Enabled bool
+		Port    int `required:"true"`
+	}
+}
+
+type backends struct {
+	Queue struct {
+		Type  string `selects:"Kafka,SQS"`
+		Kafka struct {
+			Brokers []string `required:"true"`
+		}
+		SQS struct {
+			URL string `required:"true"`
+		}
+	}
+}
+
+type required struct {
+	Token string `required:"true"`
+}
+
+type mirrored struct {
+	DatabaseURL string `envconfig:"DATABASE_URL"`
+	LegacyDB    string `envconfig:"DB" mirror:"DatabaseURL"`
+}
+
+type constrained struct {
+	MinConns   int `lte_field:"MaxConns"`
+	MaxConns   int
+	TLSEnabled bool `requires:"TLSCert"`
+	TLSCert    string
+}
+
+type types struct {
+	Listen  rd.HostPort `port:"8080"`
+	API     url.URL
+	Labels  map[string]string
+	Version rd.SemVer
+	Cache   rd.ByteSize
+}
+
+// level is an enum that only knows how to read itself from JSON
+type level int
+
+func (l *level) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+
+	switch name {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", name)
+	}
+	return nil
+}
+
+type logging struct {
+	Level  level
+	Levels []level
+}
+
+type window struct {
+	From int
+	To   int
+}
+
+func (w window) Validate() error {
+	if w.From > w.To {
+		return errors.New("From must not be after To")
+	}
+	return nil
+}
+
+type maintenance struct {
+	Window window
+}
+
+type ruled struct {
+	Port    int           `validate:"min=1,max=65535"`
+	Env     string        `validate:"oneof=dev staging prod"`
+	Webhook string        `validate:"omitempty,url"`
+	Timeout time.Duration `validate:"max=1m"`
+}
+
+type scheduled struct {
+	StartAt time.Time
+	Until   *time.Time
+}
+
+// widths packs narrow fields next to each other, so a value written at the wrong size would spill into its neighbour
+type widths struct {
+	I8  int8
+	I16 int16
+	I32 int32
+	I64 int64
+	U8  uint8
+	U16 uint16
+	U32 uint32
+	U64 uint64
+	F32 float32
+	End int8
+}
+
+type shorts struct {
+	Port    int  `short:"p" default:"8080"`
+	Verbose bool `short:"v"`
+	Name    string
+}
+
+type negated struct {
+	Color bool `default:"true"`
+}
+
+type runtimeOnly struct {
+	Port  int
+	Cache map[string]chan int `envconfig:"-"`
+	Token string              `json:"-"`
+}
+
+type renamed struct {
+	Timeout int  `deprecated:"WAIT"`
+	Debug   bool `deprecated:"VERBOSE"`
+}
+
+type aliased struct {
+	DBHost string `envconfig:"DB_HOST,DATABASE_HOST,PGHOST"`
+	DBPort int    `envconfig:"DB_PORT,PGPORT"`
+}
+
+type expanded struct {
+	DBPass      string `envconfig:"DB_PASS"`
+	DBPort      int    `envconfig:"DB_PORT"`
+	DatabaseURL string `envconfig:"DATABASE_URL"`
+	Price       string
+}
+
+type templated struct {
+	Host    string `default:"localhost"`
+	Port    int    `default:"8080"`
+	Addr    string `default:"{{ .Host }}:{{ .Port }}"`
+	BaseURL string `default:"http://{{ .Addr }}/api"`
+}
+
+type secrets struct {
+	DBPassword string `envconfig:"DB_PASSWORD"`
+	APIKey     string `envconfig:"API_KEY"`
+	Port       int    `envconfig:"PORT"`
+	TLSKey     []byte `envconfig:"TLS_KEY"`
+	DSN        string `envconfig:"DSN"`
+}
+
+type encoded struct {
+	TLSKey []byte `envconfig:"TLS_KEY"`
+	Banner string
+}
+
+type counted struct {
+	Verbose int `flagtype:"count" short:"v"`
+}
+
+type resolved struct {
+	Password string
+}
+
+// All holds an Example for every feature described in the README
+var All = []Example{
+	{
+		Name:   "tags",
+		Doc:    "envconfig sets the env, envcli the flag, and untagged fields use their upper-cased name",
+		Config: &basic{},
+		Env:    map[string]string{"TEST_STRING": "from env", "PASS": "true"},
+		Args:   []string{"-testint", "5", "-TESTFLOAT", "3.14"},
+		Want:   basic{TestString: "from env", TestInt: 5, TestFloat: 3.14, Pass: true},
+	},
+	{
+		Name:   "defaults",
+		Doc:    "default tags fill fields nothing else set, and values already on the struct win over them",
+		Config: &defaults{Port: 9090},
+		Want:   defaults{Port: 9090, Timeout: 5 * time.Second, Hosts: []string{"a", "b"}},
+	},
+	{
+		Name:   "platform-defaults",
+		Doc:    "default_<goos>, default_<goarch> and default_<goos>_<goarch> tags override default on matching platforms",
+		Config: &platform{},
+		Want: func() platform {
+			switch runtime.GOOS {
+			case "windows":
+				return platform{DataDir: `C:\ProgramData\app`}
+			case "darwin":
+				return platform{DataDir: "/usr/local/var/app"}
+			default:
+				return platform{DataDir: "/var/lib/app"}
+			}
+		}(),
+	},
+	{
+		Name:   "build-info",
+		Doc:    "buildinfo tags default to the build info embedded in the binary, falling back to default tags",
+		Config: &build{},
+		Want:   build{GoVersion: runtime.Version(), Commit: "unknown"},
+	},
+	{
+		Name:   "auto-fields",
+		Doc:    "auto tags default to the hostname, pid and start time of the process, and can still be overridden",
+		Config: &instance{},
+		Env:    map[string]string{"INSTANCE": "web-1"},
+		Want:   instance{Instance: "web-1", PID: os.Getpid()},
+	},
+	{
+		Name:   "nested-prefix",
+		Doc:    "nested fields are prefixed with their parents, noprefix leaves them bare, and WithPrefix prefixes every env",
+		Config: &nested{},
+		Env:    map[string]string{"APP_SERVER_HOST": "api", "APP_HOST": "admin"},
+		Args:   []string{"-SERVER-PORT", "443"},
+		Options: []rd.LoadOptions{
+			rd.WithPrefix("APP"),
+		},
+		Want: nested{Server: server{Host: "api", Port: 443}, Admin: server{Host: "admin"}},
+	},
+	{
+		Name:   "config-file",
+		Doc:    "a YAML file is the lowest layer, below env and cli",
+		Config: &files{},
+		Env:    map[string]string{"PORT": "9000"},
+		Files:  map[string]string{"config.yaml": "name: from file\nport: 80\nhosts:\n  - a\n  - b\n"},
+		Options: []rd.LoadOptions{
+			rd.WithConfigFile("config.yaml"),
+		},
+		Want: files{Name: "from file", Port: 9000, Hosts: []string{"a", "b"}},
+	},
+	{
+		Name:   "layered-files",
+		Doc:    "later files override earlier ones, and the format follows the extension",
+		Config: &files{},
+		Files: map[string]string{
+			"defaults.json": `{"name": "default", "port": 80}`,
+			"config.toml":   "port = 8080\n",
+		},
+		Options: []rd.LoadOptions{
+			rd.FromJSON("defaults.json"),
+			rd.FromTOML("config.toml"),
+		},
+		Want: files{Name: "default", Port: 8080},
+	},
+	{
+		Name:   "ini-file",
+		Doc:    "INI sections fill nested structs and repeated keys fill slices, composed with the other layers",
+		Config: &legacy{},
+		Env:    map[string]string{"SERVER_PORT": "9090"},
+		Files: map[string]string{
+			"legacy.ini": "; legacy config\nName = \"billing\"\n\n[server]\nhost = 0.0.0.0\nport = 8080\n" +
+				"allow = 10.0.0.0/8\nallow = 192.168.0.0/16\n\n[server.tls]\ncert_file = /etc/tls.crt ; inline\n",
+		},
+		Options: []rd.LoadOptions{
+			rd.FromINI("legacy.ini"),
+		},
+		Want: func() (want legacy) {
+			want.Name = "billing"
+			want.Server.Host = "0.0.0.0"
+			want.Server.Port = 9090
+			want.Server.Allow = []string{"10.0.0.0/8", "192.168.0.0/16"}
+			want.Server.TLS.Cert = "/etc/tls.crt"
+			return want
+		}(),
+	},
+	{
+		Name:   "dotenv",
+		Doc:    "a .env file is read into the env layer, below variables already set in the environment",
+		Config: &basic{},
+		Env:    map[string]string{"TESTINT": "7"},
+		Files: map[string]string{
+			".env": "# local development\nexport TEST_STRING=\"two\\nlines\"\nTESTINT=1\nTESTFLOAT=2.5 # inline comment\n",
+		},
+		Options: []rd.LoadOptions{
+			rd.WithDotEnv(".env"),
+		},
+		Want: basic{TestString: "two\nlines", TestInt: 7, TestFloat: 2.5},
+	},
+	{
+		Name:   "arg-files",
+		Doc:    "@path args are replaced by the flags in the file, one per line",
+		Config: &basic{},
+		Args:   []string{"@ci/flags.txt", "-PASS"},
+		Files: map[string]string{
+			"ci/flags.txt": "# generated by ci\n-TEST_STRING 'built on ci'\n-testint=3\n",
+		},
+		Options: []rd.LoadOptions{
+			rd.WithArgFiles(),
+		},
+		Want: basic{TestString: "built on ci", TestInt: 3, Pass: true},
+	},
+	{
+		Name:   "indexed-env",
+		Doc:    "slices are read from numbered env vars in order when the plain one isn't set, so items can hold commas",
+		Config: &lists{},
+		Env: map[string]string{
+			"APP_HOSTS_0":  "a,1",
+			"APP_HOSTS_1":  "b,2",
+			"APP_PORTS_0":  "80",
+			"APP_PORTS_1":  "443",
+			"APP_PORTS_3":  "8080",
+			"APP_ROUTES_0": `{"path": "/", "backend": "web"}`,
+		},
+		Options: []rd.LoadOptions{
+			rd.WithPrefix("APP"),
+		},
+		Want: lists{
+			Hosts:  []string{"a,1", "b,2"},
+			Ports:  []int{80, 443},
+			Routes: []route{{Path: "/", Backend: "web"}},
+		},
+	},
+	{
+		Name:   "precedence",
+		Doc:    "WithPrecedence reorders the layers, here env beats cli",
+		Config: &basic{},
+		Env:    map[string]string{"TESTINT": "1"},
+		Args:   []string{"-testint", "2"},
+		Options: []rd.LoadOptions{
+			rd.WithPrecedence(rd.Env, rd.CLI, rd.Default),
+		},
+		Want: basic{TestInt: 1},
+	},
+	{
+		Name:    "required",
+		Doc:     "required fields left at their zero value are reported with the flag and env that set them",
+		Config:  &required{},
+		WantErr: "TOKEN",
+	},
+	{
+		Name:    "strict",
+		Doc:     "WithStrict reports values that don't parse, with the key, raw value and expected type",
+		Config:  &basic{},
+		Env:     map[string]string{"TESTINT": "abc"},
+		Options: []rd.LoadOptions{rd.WithStrict()},
+		WantErr: `TestInt (TESTINT): invalid value "abc", expected int`,
+	},
+	{
+		Name:    "unknown-keys",
+		Doc:     "WithStrict also reports keys in a config file that no field reads, such as a misspelt one",
+		Config:  &files{},
+		Files:   map[string]string{"config.yaml": "name: from file\nprot: 80\n"},
+		Options: []rd.LoadOptions{rd.WithConfigFile("config.yaml"), rd.WithStrict()},
+		WantErr: "prot: unknown key in config.yaml",
+	},
+	{
+		Name:    "all-errors",
+		Doc:     "every value that fails and every missing required field is reported in one LoadError",
+		Config:  &types{},
+		Env:     map[string]string{"LISTEN": "localhost:0", "VERSION": "one"},
+		Args:    []string{"-API", "https://api.example.com"},
+		WantErr: "2 problems loading config:\n  Listen (LISTEN)",
+	},
+	{
+		Name:    "unknown-flag",
+		Doc:     "with flag.ContinueOnError a bad cli arg is returned as an error instead of exiting",
+		Config:  &basic{},
+		Args:    []string{"-nope"},
+		WantErr: "flag provided but not defined: -nope",
+	},
+	{
+		Name:   "enabled-switch",
+		Doc:    "a nested struct whose Enabled field is false is left zero and its fields aren't validated",
+		Config: &switched{},
+		Env:    map[string]string{"TLS_CERT": "/etc/tls.crt", "METRICS_ENABLED": "true", "METRICS_PORT": "9100"},
+		Want: func() (want switched) {
+			want.Metrics.Enabled = true
+			want.Metrics.Port = 9100
+			return want
+		}(),
+	},
+	{
+		Name:   "select-branch",
+		Doc:    "a selects tag picks the one nested struct that is read and validated, the others are left zero",
+		Config: &backends{},
+		Env:    map[string]string{"QUEUE_TYPE": "sqs", "QUEUE_SQS_URL": "https://sqs.example.com/q", "QUEUE_KAFKA_BROKERS": "a"},
+		Want: func() (want backends) {
+			want.Queue.Type = "sqs"
+			want.Queue.SQS.URL = "https://sqs.example.com/q"
+			return want
+		}(),
+	},
+	{
+		Name:   "mirror",
+		Doc:    "a legacy field set by env is copied to the field it mirrors",
+		Config: &mirrored{},
+		Env:    map[string]string{"DB": "postgres://legacy"},
+		Want:   mirrored{DatabaseURL: "postgres://legacy", LegacyDB: "postgres://legacy"},
+	},
+	{
+		Name:    "constraints",
+		Doc:     "every broken constraint between fields is reported together",
+		Config:  &constrained{},
+		Env:     map[string]string{"MINCONNS": "10", "MAXCONNS": "5", "TLSENABLED": "true"},
+		WantErr: "TLSCert",
+	},
+	{
+		Name:   "field-types",
+		Doc:    "HostPort, url.URL, maps, SemVer and ByteSize are parsed and validated from env and cli",
+		Config: &types{},
+		Env:    map[string]string{"LISTEN": "localhost", "LABELS": "team=core,env=prod", "CACHE": "1.5GiB"},
+		Args:   []string{"-API", "https://api.example.com", "-VERSION", "v1.2.3"},
+		Want: types{
+			Listen:  rd.HostPort{Host: "localhost", Port: "8080"},
+			API:     url.URL{Scheme: "https", Host: "api.example.com"},
+			Labels:  map[string]string{"team": "core", "env": "prod"},
+			Version: rd.SemVer{Major: 1, Minor: 2, Patch: 3},
+			Cache:   1536 << 20,
+		},
+	},
+	{
+		Name:   "text-unmarshalers",
+		Doc:    "fields with their own UnmarshalText, like time.Time, get a flag and are read from env through it",
+		Config: &scheduled{},
+		Env:    map[string]string{"UNTIL": "2024-02-01T00:00:00Z"},
+		Args:   []string{"-STARTAT", "2024-01-01T09:30:00Z"},
+		Want: scheduled{
+			StartAt: time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC),
+			Until:   func() *time.Time { t := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); return &t }(),
+		},
+	},
+	{
+		Name:   "json-enums",
+		Doc:    "named types that only implement json.Unmarshaler are read through it, with plain values passed as strings",
+		Config: &logging{},
+		Env:    map[string]string{"LEVEL": "debug"},
+		Args:   []string{"-LEVELS", "info,debug"},
+		Want:   logging{Level: 1, Levels: []level{2, 1}},
+	},
+	{
+		Name:   "integer-widths",
+		Doc:    "every int, uint and float width is set at its own size from env and cli, leaving the fields around it alone",
+		Config: &widths{End: 7},
+		Env: map[string]string{
+			"I8": "-128", "I16": "-32768", "I32": "-2147483648", "I64": "-1",
+			"U8": "255", "U16": "65535", "U32": "4294967295",
+		},
+		Args: []string{"-U64", "18446744073709551615", "-F32", "1.5"},
+		Want: widths{
+			I8: -128, I16: -32768, I32: -2147483648, I64: -1,
+			U8: 255, U16: 65535, U32: 4294967295, U64: 18446744073709551615,
+			F32: 1.5, End: 7,
+		},
+	},
+	{
+		Name:   "unsigned-cli",
+		Doc:    "uint fields of every width take their value from the cli over env, set as the args are parsed",
+		Config: &widths{},
+		Env:    map[string]string{"U8": "1", "U16": "1", "U32": "1", "U64": "1"},
+		Args:   []string{"-U64", "9", "-U32", "4000000000", "-U16", "60000", "-U8", "200"},
+		Want:   widths{U8: 200, U16: 60000, U32: 4000000000, U64: 9},
+	},
+	{
+		Name:    "validator",
+		Doc:     "structs with a Validate method are checked once loaded, with nested errors prefixed by their path",
+		Config:  &maintenance{},
+		Env:     map[string]string{"WINDOW_FROM": "22", "WINDOW_TO": "6"},
+		WantErr: "Window: From must not be after To",
+	},
+	{
+		Name:    "validate-tags",
+		Doc:     "validate: tags bound numbers and durations, limit a field to a list of values and check URLs",
+		Config:  &ruled{},
+		Env:     map[string]string{"PORT": "70000", "ENV": "prod", "TIMEOUT": "2m"},
+		WantErr: "Port (PORT): must be at most 65535, got 70000, Timeout (TIMEOUT): must be at most 1m, got 2m0s",
+	},
+	{
+		Name:   "resolver",
+		Doc:    "a Resolver replaces references in string fields once every layer is merged",
+		Config: &resolved{},
+		Env:    map[string]string{"PASSWORD": "secret://db"},
+		Options: []rd.LoadOptions{
+			rd.WithResolver(rd.ResolverFunc(func(ctx context.Context, value string) (string, bool, error) {
+				if !strings.HasPrefix(value, "secret://") {
+					return "", false, nil
+				}
+				return "hunter2", true, nil
+			})),
+		},
+		Want: resolved{Password: "hunter2"},
+	},
+	{
+		Name:    "encrypted",
+		Doc:     "Decrypter resolves enc: values with a KeyProvider, here a StaticKey",
+		Config:  &resolved{},
+		Env:     map[string]string{"PASSWORD": "enc:pC0cljAEYTrQX7fzEmJLWzXv0Bwr2x+dIcpJn5CjuWDgNZA="},
+		Options: []rd.LoadOptions{rd.WithResolver(rd.Decrypter(rd.StaticKey("0123456789abcdef0123456789abcdef")))},
+		Want:    resolved{Password: "hunter2"},
+	},
+	{
+		Name:    "route-overrides",
+		Doc:     "a route reads a copy of the loaded config under its own prefix, as httpconfig.Route does",
+		Config:  &defaults{Port: 8080, Timeout: 5 * time.Second, Hosts: []string{"a"}},
+		Env:     map[string]string{"USERS_TIMEOUT": "30s"},
+		Options: []rd.LoadOptions{rd.WithPrefix("USERS")},
+		Want:    defaults{Port: 8080, Timeout: 30 * time.Second, Hosts: []string{"a"}},
+	},
+	{
+		Name:   "defaults-file",
+		Doc:    "a defaults file shipped with the binary is read below every other file when its sha256 matches",
+		Config: &defaults{},
+		Files:  map[string]string{"defaults.yaml": "port: 9000\n", "config.yaml": "timeout: 10s\n"},
+		Options: []rd.LoadOptions{
+			rd.WithDefaultsFile("defaults.yaml", "ef809744031f6175e7b9c1ef653d46efd4f7a4be90db9f194f9bf2d20a8f2193"),
+			rd.WithConfigFile("config.yaml"),
+		},
+		Want: defaults{Port: 9000, Timeout: 10 * time.Second, Hosts: []string{"a", "b"}},
+	},
+	{
+		Name:   "defaults-file-changed",
+		Doc:    "a defaults file changed after the build is refused",
+		Config: &defaults{},
+		Files:  map[string]string{"defaults.yaml": "port: 9001\n"},
+		Options: []rd.LoadOptions{
+			rd.WithDefaultsFile("defaults.yaml", "ef809744031f6175e7b9c1ef653d46efd4f7a4be90db9f194f9bf2d20a8f2193"),
+		},
+		WantErr: "checksum mismatch",
+	},
+	{
+		Name:   "defaults-file-no-sum",
+		Doc:    "a defaults file with no sha256 given is refused rather than read unchecked",
+		Config: &defaults{},
+		Files:  map[string]string{"defaults.yaml": "port: 9000\n"},
+		Options: []rd.LoadOptions{
+			rd.WithDefaultsFile("defaults.yaml", ""),
+		},
+		WantErr: "no sha256 given",
+	},
+	{
+		Name:   "short-flags",
+		Doc:    "short: tags add single-letter aliases, and flags can be written GNU-style as --name=value",
+		Config: &shorts{},
+		Args:   []string{"-v", "-p", "9000", "--NAME=api"},
+		Want:   shorts{Port: 9000, Verbose: true, Name: "api"},
+	},
+	{
+		Name:   "negated-bool",
+		Doc:    "every bool flag gets a -no- counterpart that sets it to false",
+		Config: &negated{},
+		Args:   []string{"--no-COLOR"},
+		Want:   negated{Color: false},
+	},
+	{
+		Name:   "count-flag",
+		Doc:    "flagtype:\"count\" counts how many times a flag is given, starting from 0 over the env",
+		Config: &counted{},
+		Env:    map[string]string{"VERBOSE": "1"},
+		Args:   []string{"-v", "-v", "--VERBOSE"},
+		Want:   counted{Verbose: 3},
+	},
+	{
+		Name:   "repeated-slice-flag",
+		Doc:    "a repeated slice flag adds to the items before it, replacing the env value the first time",
+		Config: &lists{},
+		Env:    map[string]string{"HOSTS": "env"},
+		Args:   []string{"-HOSTS", "a", "-HOSTS", "b,c", "-PORTS", "80"},
+		Want:   lists{Hosts: []string{"a", "b", "c"}, Ports: []int{80}},
+	},
+	{
+		Name:   "ignored-fields",
+		Doc:    "fields tagged envconfig:\"-\", envcli:\"-\" or json:\"-\" get no flag, env or file value",
+		Config: &runtimeOnly{},
+		Env:    map[string]string{"PORT": "8080", "TOKEN": "secret"},
+		Want:   runtimeOnly{Port: 8080},
+	},
+	{
+		Name:   "deprecated-names",
+		Doc:    "a deprecated: tag keeps the old env and flag of a renamed field working, with a warning",
+		Config: &renamed{},
+		Env:    map[string]string{"WAIT": "30"},
+		Args:   []string{"-VERBOSE"},
+		Options: []rd.LoadOptions{
+			rd.WithDiagnostics(func(rd.Diagnostic) {}),
+		},
+		Want: renamed{Timeout: 30, Debug: true},
+	},
+	{
+		Name:   "env-aliases",
+		Doc:    "the names after the first in envconfig: are read in order when the first isn't set",
+		Config: &aliased{},
+		Env:    map[string]string{"PGHOST": "pg", "DATABASE_HOST": "db", "DB_PORT": "5432", "PGPORT": "6432"},
+		Want:   aliased{DBHost: "db", DBPort: 5432},
+	},
+	{
+		Name:   "unknown-env",
+		Doc:    "WithUnknownEnv reports envs with the prefix that no field reads, suggesting the one that was meant",
+		Config: &defaults{},
+		Env:    map[string]string{"APP_PORT": "9000", "APP_TIMEOT": "5s"},
+		Options: []rd.LoadOptions{
+			rd.WithPrefix("APP"),
+			rd.WithUnknownEnv("APP", rd.LevelError),
+		},
+		WantErr: "APP_TIMEOT: unknown key in env, no field reads it, did you mean APP_TIMEOUT?",
+	},
+	{
+		Name:   "fuzzy-env",
+		Doc:    "WithFuzzyEnv matches env names regardless of case and of - or _ between words",
+		Config: &nested{},
+		Env:    map[string]string{"app-server-host": "api", "App_Server_Port": "8443"},
+		Options: []rd.LoadOptions{
+			rd.WithPrefix("APP"),
+			rd.WithFuzzyEnv(),
+		},
+		Want: nested{Server: server{Host: "api", Port: 8443}},
+	},
+	{
+		Name:   "expand",
+		Doc:    "WithExpand fills ${NAME} in from other fields or the env, with $$ for a literal $",
+		Config: &expanded{},
+		Env: map[string]string{
+			"APP_DB_PASS":      "s3cret",
+			"APP_DB_PORT":      "5432",
+			"APP_DATABASE_URL": "postgres://app:${DB_PASS}@${DB_HOST}:${APP_DB_PORT}/app",
+			"APP_PRICE":        "$$5",
+			"DB_HOST":          "db",
+		},
+		Options: []rd.LoadOptions{
+			rd.WithPrefix("APP"),
+			rd.WithExpand(),
+		},
+		Want: expanded{
+			DBPass:      "s3cret",
+			DBPort:      5432,
+			DatabaseURL: "postgres://app:s3cret@db:5432/app",
+			Price:       "$5",
+		},
+	},
+	{
+		Name:   "templates",
+		Doc:    "WithTemplates renders values from other fields, after any templated field they refer to",
+		Config: &templated{},
+		Env:    map[string]string{"HOST": "api.internal"},
+		Options: []rd.LoadOptions{
+			rd.WithTemplates(),
+		},
+		Want: templated{Host: "api.internal", Port: 8080, Addr: "api.internal:8080", BaseURL: "http://api.internal:8080/api"},
+	},
+	{
+		Name:   "secret-files",
+		Doc:    "WithSecretFiles reads NAME_FILE envs into fields of any type, and file:// values into strings",
+		Config: &secrets{},
+		Env: map[string]string{
+			"DB_PASSWORD_FILE": "/run/secrets/db_password",
+			"API_KEY":          "file:///run/secrets/api_key",
+			"PORT_FILE":        "/run/secrets/port",
+			"TLS_KEY_FILE":     "/run/secrets/tls_key",
+			"DSN":              "postgres://app:${DB_PASSWORD}@db/app",
+		},
+		Files: map[string]string{
+			"/run/secrets/db_password": "hunter2\n",
+			"/run/secrets/api_key":     "k-123\n",
+			"/run/secrets/port":        "5432\n",
+			"/run/secrets/tls_key":     "key",
+		},
+		Options: []rd.LoadOptions{
+			rd.WithSecretFiles(),
+			rd.WithExpand(),
+		},
+		Want: secrets{
+			DBPassword: "hunter2",
+			APIKey:     "k-123",
+			Port:       5432,
+			TLSKey:     []byte("key"),
+			DSN:        "postgres://app:hunter2@db/app",
+		},
+	},
+	{
+		Name:   "base64-values",
+		Doc:    "string and []byte values starting with base64: are decoded before they are set",
+		Config: &encoded{},
+		Env:    map[string]string{"TLS_KEY": "base64:AAECAw=="},
+		Args:   []string{"-BANNER", "base64:aGVsbG8sIHdvcmxk"},
+		Want:   encoded{TLSKey: []byte{0, 1, 2, 3}, Banner: "hello, world"},
+	},
+}
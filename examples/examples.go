@@ -0,0 +1,102 @@
+// Package examples runs documented ruadan configurations end to end, in process, from a set of env vars, cli args and
+// config files. Each Example is both documentation of a feature and a check that it still works, and All holds the
+// examples for every feature described in the README. Run them with:
+//
+//	go run github.com/bit-cmdr/ruadan/cmd/ruadan-examples
+//
+// go test runs them as well
+package examples
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing/fstest"
+
+	rd "github.com/bit-cmdr/ruadan"
+)
+
+// Example is a config struct loaded from Env, Args and Files, along with the result it should load to
+type Example struct {
+	// Name identifies the example, e.g. nested-prefix
+	Name string
+	// Doc describes the feature the example shows
+	Doc string
+	// Config is a pointer to the struct to load. Values already set on it are used as defaults, and it is copied
+	// before every run so an Example can be run any number of times
+	Config interface{}
+	// Env holds the env vars visible to the load, nothing else from the process env is read
+	Env map[string]string
+	// Args are the cli args
+	Args []string
+	// Files maps paths to the contents of config files, read with rd.WithFS
+	Files map[string]string
+	// Options are passed to the loader after the ones set up from Env, Args and Files
+	Options []rd.LoadOptions
+	// Want is the struct Config should hold after loading, compared with reflect.DeepEqual
+	Want interface{}
+	// WantErr, when set, is text the load error must contain, and Want is ignored
+	WantErr string
+}
+
+// Result is the outcome of running an Example
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Run loads a copy of Config and checks it against Want, or the error against WantErr
+func (e Example) Run(ctx context.Context) error {
+	v := reflect.ValueOf(e.Config)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return rd.ErrInvalidConfig
+	}
+
+	cfg := reflect.New(v.Elem().Type())
+	cfg.Elem().Set(v.Elem())
+
+	files := fstest.MapFS{}
+	for path, content := range e.Files {
+		files[strings.TrimPrefix(path, "/")] = &fstest.MapFile{Data: []byte(content)}
+	}
+
+	options := append([]rd.LoadOptions{
+		rd.WithArgs(e.Args),
+		rd.WithSources(rd.MapSource(e.Env)),
+		rd.WithFS(files),
+		rd.WithDiagnostics(func(rd.Diagnostic) {}),
+		rd.WithErrorHandling(flag.ContinueOnError),
+		rd.WithOutput(ioutil.Discard),
+	}, e.Options...)
+
+	err := rd.NewLoader(options...).Load(ctx, cfg.Interface())
+	switch {
+	case e.WantErr != "" && err == nil:
+		return fmt.Errorf("expected an error containing %q, got none", e.WantErr)
+	case e.WantErr != "" && !strings.Contains(err.Error(), e.WantErr):
+		return fmt.Errorf("expected an error containing %q, got %q", e.WantErr, err)
+	case e.WantErr != "":
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if got := cfg.Elem().Interface(); !reflect.DeepEqual(got, e.Want) {
+		return fmt.Errorf("loaded %+v, want %+v", got, e.Want)
+	}
+
+	return nil
+}
+
+// RunAll runs every example in order and returns a Result for each
+func RunAll(ctx context.Context, examples []Example) []Result {
+	results := make([]Result, 0, len(examples))
+	for _, e := range examples {
+		results = append(results, Result{Name: e.Name, Err: e.Run(ctx)})
+	}
+
+	return results
+}
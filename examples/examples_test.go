@@ -0,0 +1,14 @@
+package examples
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	for _, r := range RunAll(context.Background(), All) {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Name, r.Err)
+		}
+	}
+}
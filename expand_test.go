@@ -0,0 +1,119 @@
+package ruadan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	type config struct {
+		User string `envconfig:"USER"`
+		Pass string `envconfig:"PASS"`
+		Port int    `envconfig:"PORT" default:"5432"`
+		URL  string `envconfig:"URL"`
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "fields",
+			env: map[string]string{
+				"APP_USER": "app",
+				"APP_PASS": "pw",
+				"APP_URL":  "pg://${APP_USER}:${APP_PASS}@db:${APP_PORT}",
+			},
+			want: "pg://app:pw@db:5432",
+		},
+		{
+			name: "without prefix",
+			env:  map[string]string{"APP_USER": "app", "APP_URL": "${USER}@${PORT}"},
+			want: "app@5432",
+		},
+		{
+			name: "field from cli",
+			env:  map[string]string{"APP_URL": "${APP_USER}"},
+			args: []string{"-USER", "cli"},
+			want: "cli",
+		},
+		{
+			name: "nested field",
+			env:  map[string]string{"APP_USER": "${APP_PASS}", "APP_PASS": "pw", "APP_URL": "${APP_USER}"},
+			want: "pw",
+		},
+		{
+			name: "source",
+			env:  map[string]string{"REGION": "eu", "APP_URL": "${REGION}.example.com"},
+			want: "eu.example.com",
+		},
+		{
+			name: "escaped",
+			env:  map[string]string{"APP_URL": "$$HOME and $HOME"},
+			want: "$HOME and $HOME",
+		},
+		{
+			name:    "unknown",
+			env:     map[string]string{"APP_URL": "${NOPE}"},
+			wantErr: "${NOPE}",
+		},
+		{
+			name:    "loop",
+			env:     map[string]string{"APP_USER": "${APP_PASS}", "APP_PASS": "${APP_USER}"},
+			wantErr: "reference loop",
+		},
+		{
+			name:    "unclosed",
+			env:     map[string]string{"APP_URL": "${APP_USER"},
+			wantErr: "unclosed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config
+			err := testLoad(&cfg, tt.env, tt.args, nil, WithPrefix("APP"), WithExpand())
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case cfg.URL != tt.want:
+				t.Errorf("expanded to %q, want %q", cfg.URL, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandUnknownVariable(t *testing.T) {
+	var cfg struct {
+		URL string `envconfig:"URL"`
+	}
+	err := testLoad(&cfg, map[string]string{"URL": "${NOPE}"}, nil, nil, WithExpand())
+	if !errors.Is(err, ErrUnknownVariable) {
+		t.Errorf("expected ErrUnknownVariable, got %v", err)
+	}
+
+	var fe *FieldError
+	if !errors.As(err, &fe) || fe.Key != "URL" {
+		t.Errorf("expected a FieldError for URL, got %v", err)
+	}
+}
+
+func TestExpandOff(t *testing.T) {
+	var cfg struct {
+		URL string `envconfig:"URL"`
+	}
+	if err := testLoad(&cfg, map[string]string{"URL": "${NOPE}"}, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.URL != "${NOPE}" {
+		t.Errorf("expanded without WithExpand: %q", cfg.URL)
+	}
+}
@@ -0,0 +1,115 @@
+package ruadan
+
+import "flag"
+
+// FieldOrigin is the value a field ended up with after a load and where it came from, to answer why a field has the
+// value it has without guessing which layer won
+type FieldOrigin struct {
+	// Field is the dotted path of the field, e.g. Server.Port
+	Field string
+	Env   string
+	// Value is formatted the same way as FormatValue, with secrets as Redacted
+	Value string
+	Layer Layer
+	// Source names what in the layer set the value, e.g. flag -port, env PORT, file config.yaml, default tag or value
+	// passed in. It is empty when nothing set the field, and switched off for a field of a struct switched off by
+	// its Enabled or a selects: field
+	Source string
+}
+
+// String formats the FieldOrigin as a single line, e.g. Server.Port = 8080 (env SERVER_PORT)
+func (o FieldOrigin) String() string {
+	source := o.Source
+	if source == "" {
+		source = "not set"
+	}
+
+	return o.Field + " = " + quoteEmpty(o.Value) + " (" + source + ")"
+}
+
+// origin is the layer a lookup found the value of a field in, recorded while loading
+type origin struct {
+	layer  Layer
+	source string
+}
+
+// Explain returns a FieldOrigin for every field of the struct from the last call to Load, in the order of the struct,
+// or nil if nothing has been loaded. Failed loads are explained too, as long as the cli could be parsed
+func (l *Loader) Explain() []FieldOrigin {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]FieldOrigin(nil), l.origins...)
+}
+
+// record keeps the layer a value was found in while opt.origins is set
+func (opt *LoadOption) record(key string, layer Layer, source string) {
+	if opt.origins != nil {
+		opt.origins[key] = origin{layer: layer, source: source}
+	}
+}
+
+// explainFields works out where the value of every field came from once the cli is parsed. A flag wins over what the
+// lookups found when cli is ranked above their layer, and a mirrored field takes the origin of whichever side was set
+func explainFields(fs *flag.FlagSet, metas, active []fieldMeta, opt *LoadOption) []FieldOrigin {
+	set := setFlagNames(fs)
+	cli := layerRank(opt.precedence, CLI)
+
+	origins := make(map[string]origin, len(metas))
+	for _, meta := range metas {
+		o := opt.origins[tagENV(meta)]
+		rank := layerRank(opt.precedence, o.layer)
+		if set[tagCLI(meta)] && cli >= 0 && (o.source == "" || o.layer == Default || rank < 0 || cli < rank) {
+			o = origin{layer: CLI, source: "flag -" + tagCLI(meta)}
+		}
+		if o.source == "" {
+			o.layer = Default
+		}
+		origins[fieldPath(meta)] = o
+	}
+
+	for _, meta := range metas {
+		name := meta.Tags.Get("mirror")
+		if name == "" {
+			continue
+		}
+
+		target, ok := findSibling(metas, meta, name)
+		if !ok {
+			continue
+		}
+
+		from, to := fieldPath(meta), fieldPath(target)
+		if origins[to].source != "" || origins[from].source == "" {
+			from, to = to, from
+		}
+		o := origins[from]
+		if o.source != "" {
+			o.source += " through " + from
+		}
+		origins[to] = o
+	}
+
+	on := make(map[string]bool, len(active))
+	for _, meta := range active {
+		on[fieldPath(meta)] = true
+	}
+
+	infos := make([]FieldOrigin, len(metas))
+	for i, meta := range metas {
+		path := fieldPath(meta)
+		o := origins[path]
+		if !on[path] {
+			o = origin{layer: Default, source: "switched off"}
+		}
+
+		infos[i] = FieldOrigin{
+			Field:  path,
+			Env:    tagENV(meta),
+			Value:  formatMeta(meta),
+			Layer:  o.layer,
+			Source: o.source,
+		}
+	}
+
+	return infos
+}
@@ -0,0 +1,115 @@
+package ruadan
+
+import "reflect"
+
+// Extend copies the values of base into the fields of override with the same name and type, so a component config can
+// start from a shared base config. Fields already set on override are kept, nested structs are merged field by field,
+// and slices and maps are copied rather than shared. Fields promoted through a nil embedded pointer are skipped. Both
+// must be struct pointers, and base is usually loaded first. Loading override afterwards keeps the copied
+// values as defaults, so cli, env and files can still override them:
+//
+//	var shared Shared
+//	loader.Load(ctx, &shared)
+//
+//	var worker Worker
+//	ruadan.Extend(&shared, &worker)
+//	loader.Load(ctx, &worker)
+func Extend(base, override interface{}) error {
+	b, o := reflect.ValueOf(base), reflect.ValueOf(override)
+	if b.Kind() != reflect.Ptr || o.Kind() != reflect.Ptr || b.IsNil() || o.IsNil() {
+		return ErrInvalidConfig
+	}
+
+	b, o = b.Elem(), o.Elem()
+	if b.Kind() != reflect.Struct || o.Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	extendStruct(b, o)
+	return nil
+}
+
+func extendStruct(base, override reflect.Value) {
+	bt := base.Type()
+	for i := 0; i < bt.NumField(); i++ {
+		bf := base.Field(i)
+		of := promotedField(override, bt.Field(i).Name)
+		if !of.IsValid() || !of.CanSet() || !bf.CanInterface() {
+			continue
+		}
+
+		if nestedStruct(bf) && nestedStruct(of) {
+			if bf.Kind() == reflect.Ptr {
+				if bf.IsNil() {
+					continue
+				}
+				bf = bf.Elem()
+			}
+			if of.Kind() == reflect.Ptr {
+				if of.IsNil() {
+					of.Set(reflect.New(of.Type().Elem()))
+				}
+				of = of.Elem()
+			}
+
+			extendStruct(bf, of)
+			continue
+		}
+
+		if bf.Type().AssignableTo(of.Type()) && isZero(of) {
+			of.Set(copyValue(bf))
+		}
+	}
+}
+
+// promotedField returns the field of v called name, which may be promoted from an embedded struct, or the zero Value
+// when there is none or it is reached through a nil embedded pointer, which FieldByName would panic on
+func promotedField(v reflect.Value, name string) reflect.Value {
+	sf, ok := v.Type().FieldByName(name)
+	if !ok {
+		return reflect.Value{}
+	}
+
+	for i, x := range sf.Index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+
+	return v
+}
+
+// copyValue returns v with slices and maps copied, so the base and extended configs don't share them and appending to
+// or setting a key on one never changes the other
+func copyValue(v reflect.Value) reflect.Value {
+	switch {
+	case v.Kind() == reflect.Slice && !v.IsNil():
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		return c
+	case v.Kind() == reflect.Map && !v.IsNil():
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// nestedStruct reports whether v is a struct, or pointer to one, whose fields are read one by one rather than being
+// parsed from a single value
+func nestedStruct(v reflect.Value) bool {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && t != ipNetType && !parsesItself(t)
+}
@@ -0,0 +1,286 @@
+package ruadan
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// ConfigFileFlag is the cli flag that overrides the path passed to GetConfigFlagSetWithFile
+	ConfigFileFlag = "config"
+	// ConfigFileEnv is the env that overrides the path passed to GetConfigFlagSetWithFile
+	ConfigFileEnv = "CONFIG_FILE"
+)
+
+// configFile holds the flattened values read from a config file along with the format used to match field tags
+type configFile struct {
+	path   string
+	format string
+	values map[string]string
+	// sum is the hex sha256 of the file, recorded in lock files
+	sum string
+	// want is the sum the file must have, for the defaults file
+	want string
+}
+
+func (f *configFile) lookup(meta fieldMeta) (string, bool) {
+	if f == nil {
+		return "", false
+	}
+
+	val, ok := f.values[f.key(meta)]
+	return val, ok
+}
+
+// key is the key the field is read from in the file
+func (f *configFile) key(meta fieldMeta) string {
+	key := fileKey(meta, f.format)
+	if f.format == "json" || f.format == "ini" {
+		key = strings.ToLower(key)
+	}
+
+	return key
+}
+
+// unknownKeys returns a FieldError wrapping ErrUnknownKey for every key in the file that no field reads, such as a
+// misspelt one. Keys under a map field are read by the map, so they are known
+func (f *configFile) unknownKeys(metas []fieldMeta) []error {
+	known := make(map[string]bool, len(metas))
+	prefixes := []string{}
+	for _, meta := range metas {
+		key := f.key(meta)
+		known[key] = true
+		if t := meta.Field.Type(); t.Kind() == reflect.Map || t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Map {
+			prefixes = append(prefixes, key+".")
+		}
+	}
+
+	keys := make([]string, 0, len(f.values))
+	for key := range f.values {
+		if !known[key] && !hasAnyPrefix(key, prefixes) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = &FieldError{Key: key, Value: f.values[key], Err: fmt.Errorf("%w in %s", ErrUnknownKey, f.path)}
+	}
+
+	return errs
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// configFiles are read in order, so a value in a later file overrides the same value in an earlier one
+type configFiles []*configFile
+
+// unknownKeys returns the keys no field reads from every file
+func (files configFiles) unknownKeys(metas []fieldMeta) error {
+	errs := []error{}
+	for _, f := range files {
+		errs = append(errs, f.unknownKeys(metas)...)
+	}
+
+	return joinErrors(errs)
+}
+
+func (files configFiles) lookup(meta fieldMeta) (*configFile, string, bool) {
+	for i := len(files) - 1; i >= 0; i-- {
+		if val, ok := files[i].lookup(meta); ok {
+			return files[i], val, true
+		}
+	}
+
+	return nil, "", false
+}
+
+// FromYAML reads the YAML file at path as a source below env and cli
+func FromYAML(path string) LoadOptions {
+	return fromFile(path, "yaml")
+}
+
+// FromJSON reads the JSON file at path as a source below env and cli
+func FromJSON(path string) LoadOptions {
+	return fromFile(path, "json")
+}
+
+// FromTOML reads the TOML file at path as a source below env and cli. Fields are matched with the toml: tag, or the
+// lower-cased field name if there is no tag, and nested structs are read from tables
+func FromTOML(path string) LoadOptions {
+	return fromFile(path, "toml")
+}
+
+// FromINI reads the INI file at path as a source below env and cli. Fields are matched case-insensitively with the
+// ini: tag, or the field name if there is no tag, and nested structs are read from sections, e.g. [server] or
+// [server.tls]
+func FromINI(path string) LoadOptions {
+	return fromFile(path, "ini")
+}
+
+func fromFile(path, format string) LoadOptions {
+	return func(o *LoadOption) { o.files = append(o.files, &configFile{path: path, format: format}) }
+}
+
+// WithDefaultsFile reads a defaults file shipped with the binary, e.g. defaults.yaml, below every other config file,
+// so packagers can change defaults without changing code. A relative path is found next to the executable, and a
+// file that isn't there is skipped. sum is the hex sha256 of the file as shipped, usually set at build time with
+// -ldflags "-X main.defaultsSum=...", and a file that doesn't match it isn't read: the load returns an error wrapping
+// ErrChecksum, so a file changed after the build is caught rather than quietly changing the config. An empty sum is
+// an error wrapping ErrChecksum too, so a build that left it out fails rather than skipping the check
+func WithDefaultsFile(path, sum string) LoadOptions {
+	return func(o *LoadOption) { o.defaults = &configFile{path: path, format: fileFormat(path), want: sum} }
+}
+
+// defaultsFilePath returns where to read the defaults file from, which for a relative path on the operating system is
+// the directory of the executable, with symlinks followed
+func defaultsFilePath(fsys fs.FS, path string) string {
+	if fsys != nil || filepath.IsAbs(path) {
+		return path
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return path
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	return filepath.Join(filepath.Dir(exe), path)
+}
+
+// configFilePath resolves the path of the config file, preferring the cli flag, then the sources, then the given
+// path
+func configFilePath(ctx context.Context, args []string, path string, srcs sources) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+
+		name := strings.TrimLeft(arg, "-")
+		if name == arg || len(arg)-len(name) > 2 {
+			continue
+		}
+
+		switch {
+		case name == ConfigFileFlag && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(name, ConfigFileFlag+"="):
+			return strings.TrimPrefix(name, ConfigFileFlag+"=")
+		}
+	}
+
+	if _, val, ok := srcs.lookup(ctx, ConfigFileEnv); ok && val != "" {
+		return val
+	}
+
+	return path
+}
+
+// fileFormat picks the format of a config file from its extension, defaulting to yaml
+func fileFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".toml":
+		return "toml"
+	case ".ini":
+		return "ini"
+	default:
+		return "yaml"
+	}
+}
+
+func (f *configFile) read(fsys fs.FS) error {
+	r, err := openFile(fsys, f.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	h := sha256.New()
+	tr := io.TeeReader(r, h)
+	switch f.format {
+	case "json":
+		f.values, err = parseJSON(tr)
+	case "toml":
+		f.values, err = parseTOML(tr)
+	case "ini":
+		f.values, err = parseINI(tr)
+	default:
+		f.values, err = parseYAML(tr)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", f.path, err)
+	}
+
+	// the parsers can stop before the end of the file, the rest still counts towards the sum
+	if _, err = io.Copy(h, r); err != nil {
+		return fmt.Errorf("%s: %v", f.path, err)
+	}
+	f.sum = hex.EncodeToString(h.Sum(nil))
+	if f.want != "" && !strings.EqualFold(f.sum, f.want) {
+		return fmt.Errorf("%s: %w: sha256 is %s, expected %s", f.path, ErrChecksum, f.sum, f.want)
+	}
+
+	return nil
+}
+
+// openFile opens path from fsys when one is set with WithFS, otherwise from the operating system
+func openFile(fsys fs.FS, name string) (io.ReadCloser, error) {
+	if fsys == nil {
+		return os.Open(name)
+	}
+
+	return fsys.Open(strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "/"))
+}
+
+// fileKey builds the dotted path used to find a field in a file source, using the tag named by format or the
+// lower-cased field name for the field and each of its parent structs
+func fileKey(meta fieldMeta, format string) string {
+	if len(meta.Parents) == 0 {
+		return fileKeyName(meta, format)
+	}
+
+	var b strings.Builder
+	for _, m := range meta.Parents {
+		b.WriteString(fileKeyName(m, format))
+		b.WriteByte('.')
+	}
+	b.WriteString(fileKeyName(meta, format))
+
+	return b.String()
+}
+
+func fileKeyName(meta fieldMeta, format string) string {
+	name := meta.Tags.Get(format)
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		name = name[:i]
+	}
+
+	if name == "" {
+		return strings.ToLower(meta.Name)
+	}
+
+	return name
+}
@@ -0,0 +1,49 @@
+package ruadan
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDefaultsFileChecksum(t *testing.T) {
+	const data = "port: 9000\n"
+	h := sha256.Sum256([]byte(data))
+	sum := hex.EncodeToString(h[:])
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		sum     string
+		want    int
+		wantErr error
+	}{
+		{name: "matches", files: map[string]string{"defaults.yaml": data}, sum: sum, want: 9000},
+		{name: "upper-case", files: map[string]string{"defaults.yaml": data}, sum: strings.ToUpper(sum), want: 9000},
+		{name: "missing file", sum: sum, want: 8080},
+		{name: "changed", files: map[string]string{"defaults.yaml": "port: 9001\n"}, sum: sum, wantErr: ErrChecksum},
+		{name: "no sum", files: map[string]string{"defaults.yaml": data}, wantErr: ErrChecksum},
+		{name: "no sum or file", wantErr: ErrChecksum},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := struct {
+				Port int `envconfig:"PORT" json:"port" default:"8080"`
+			}{}
+			err := testLoad(&cfg, nil, nil, tt.files, WithDefaultsFile("defaults.yaml", tt.sum))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case cfg.Port != tt.want:
+				t.Errorf("loaded %d, want %d", cfg.Port, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package ruadan
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Redacted is shown instead of the value of a field tagged `secret:"true"` anywhere values are printed for people, in
+// the usage, diffs, Describe and FormatValue. Lock files and Environ still hold the real value
+const Redacted = "[redacted]"
+
+// FormatValue returns the value of the field at the dotted path field of cfg, e.g. Server.Timeout, written the same
+// way it is read from env and the same way the usage, diffs and Describe show it: durations like 30s, a ByteSize like
+// 64MiB, slices comma separated, and secrets as Redacted. It returns an empty string for a nil pointer, a field that
+// doesn't exist or a cfg that isn't a struct pointer
+func FormatValue(cfg interface{}, field string) string {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ""
+	}
+
+	metas, err := reflectConfig("", cloneStruct(v).Interface())
+	if err != nil {
+		return ""
+	}
+
+	for _, meta := range metas {
+		if fieldPath(meta) == field {
+			return formatMeta(meta)
+		}
+	}
+
+	return ""
+}
+
+// DumpSafe writes every field of cfg, which must be a struct pointer, to w one per line with its env and value, e.g.
+// Server.Port (SERVER_PORT): "8080", so the effective config can be logged at startup. Values are written the same way
+// as FormatValue, with secrets as Redacted. WithPrefix is honored and other options are ignored
+func DumpSafe(w io.Writer, cfg interface{}, options ...LoadOptions) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	metas, err := reflectConfig(newLoadOption(options...).prefix, cloneStruct(v).Interface())
+	if err != nil {
+		return err
+	}
+
+	for _, meta := range metas {
+		if _, err = fmt.Fprintf(w, "%s (%s): %q\n", fieldPath(meta), tagENV(meta), formatMeta(meta)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String returns every field of the Configuration as written by DumpSafe, with secrets redacted
+func (c *Configuration) String() string {
+	var b strings.Builder
+	_ = c.DumpSafe(&b)
+	return b.String()
+}
+
+// DumpSafe writes every field of the Configuration to w, see DumpSafe
+func (c *Configuration) DumpSafe(w io.Writer, options ...LoadOptions) error {
+	return DumpSafe(w, c.Config, options...)
+}
+
+// formatMeta writes the value of a field for people to read, hiding secrets
+func formatMeta(meta fieldMeta) string {
+	return redact(meta, valueString(meta.Field, meta.Tags))
+}
+
+// valueString writes the value of a field the way it is read from env, through the same flag.Value that parses it
+func valueString(field reflect.Value, tags reflect.StructTag) string {
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return ""
+		}
+		field = field.Elem()
+	}
+
+	if v := fieldValue(field, tags); v != nil {
+		return v.String()
+	}
+
+	return fmt.Sprint(field.Interface())
+}
+
+// isSecret reports whether the field is tagged `secret:"true"`
+func isSecret(meta fieldMeta) bool {
+	return tagBool(meta.Tags, "secret")
+}
+
+// redact replaces a value that has been set with Redacted when the field is a secret
+func redact(meta fieldMeta, value string) string {
+	if !isSecret(meta) {
+		return value
+	}
+
+	return redactValue(value)
+}
+
+// redactValue replaces a value that has been set with Redacted, leaving an unset one empty so it still reads as unset
+func redactValue(value string) string {
+	if value == "" || zeroDefault(value) {
+		return value
+	}
+
+	return Redacted
+}
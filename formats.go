@@ -0,0 +1,122 @@
+package ruadan
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// Email is an email address field type, validated as an RFC 5322 addr-spec without a display name
+type Email string
+
+// Set validates the value as an email address, used when reading from env or cli
+func (e *Email) Set(value string) error {
+	value = strings.TrimSpace(value)
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Name != "" || addr.Address != value {
+		return fmt.Errorf("invalid email address %q", value)
+	}
+
+	domain := value[strings.LastIndex(value, "@")+1:]
+	if !strings.HasPrefix(domain, "[") && !validHostname(domain) {
+		return fmt.Errorf("invalid email address %q: invalid domain", value)
+	}
+
+	*e = Email(value)
+	return nil
+}
+
+// String returns the email address
+func (e *Email) String() string {
+	if e == nil {
+		return ""
+	}
+
+	return string(*e)
+}
+
+// UUID is a field type for an RFC 4122 UUID in its 8-4-4-4-12 hex form. An optional urn:uuid: prefix or braces are
+// accepted and it is stored lower-cased without them
+type UUID string
+
+// Set validates the value as a UUID, used when reading from env or cli
+func (u *UUID) Set(value string) error {
+	s := strings.ToLower(strings.TrimSpace(value))
+	s = strings.TrimPrefix(s, "urn:uuid:")
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+
+	if len(s) != 36 {
+		return fmt.Errorf("invalid uuid %q", value)
+	}
+
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return fmt.Errorf("invalid uuid %q", value)
+			}
+		default:
+			if !(r >= '0' && r <= '9') && !(r >= 'a' && r <= 'f') {
+				return fmt.Errorf("invalid uuid %q", value)
+			}
+		}
+	}
+
+	*u = UUID(s)
+	return nil
+}
+
+// String returns the UUID
+func (u *UUID) String() string {
+	if u == nil {
+		return ""
+	}
+
+	return string(*u)
+}
+
+// Hostname is a field type for a host name validated against RFC 1123, e.g. db.internal.example.com
+type Hostname string
+
+// Set validates the value as a host name, used when reading from env or cli
+func (h *Hostname) Set(value string) error {
+	value = strings.TrimSpace(value)
+	if !validHostname(value) {
+		return fmt.Errorf("invalid hostname %q", value)
+	}
+
+	*h = Hostname(value)
+	return nil
+}
+
+// String returns the host name
+func (h *Hostname) String() string {
+	if h == nil {
+		return ""
+	}
+
+	return string(*h)
+}
+
+func validHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z') && !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '-' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
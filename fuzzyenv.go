@@ -0,0 +1,69 @@
+package ruadan
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// WithFuzzyEnv lets env names match regardless of case and of - or _ between words, so myapp-db-host or
+// Myapp_Db_Host set the field read from MYAPP_DB_HOST, as the Kubernetes downward API and some platforms don't keep
+// names upper-case. An exact match is always used first. It applies to the env, dotenv files and any MapSource,
+// which can list their names; other sources are only matched exactly
+func WithFuzzyEnv() LoadOptions {
+	return func(o *LoadOption) { o.fuzzyEnv = true }
+}
+
+type fuzzyEnvKey struct{}
+
+// fuzzyEnv maps the normalised names of each source that can list them to the names as they were set, indexed the
+// same as the sources. It travels in the context, the same as sourceStats, so the sources aren't wrapped
+type fuzzyEnv []map[string]string
+
+// withFuzzyEnv indexes the names of srcs and returns a context that matches lookups against them
+func withFuzzyEnv(ctx context.Context, srcs sources) context.Context {
+	index := make(fuzzyEnv, len(srcs))
+	for i, s := range srcs {
+		l, ok := s.(interface{ keys() []string })
+		if !ok {
+			continue
+		}
+
+		// sorted, so a name set twice with different cases always matches the same one
+		names := l.keys()
+		sort.Strings(names)
+
+		index[i] = map[string]string{}
+		for _, name := range names {
+			if n := normalizeEnv(name); index[i][n] == "" {
+				index[i][n] = name
+			}
+		}
+	}
+
+	return context.WithValue(ctx, fuzzyEnvKey{}, index)
+}
+
+func fuzzyEnvFrom(ctx context.Context) fuzzyEnv {
+	index, _ := ctx.Value(fuzzyEnvKey{}).(fuzzyEnv)
+	return index
+}
+
+// lookup reads key from the source at i under the name that matches it once normalised
+func (f fuzzyEnv) lookup(i int, s Source, key string) (string, bool) {
+	if i >= len(f) || f[i] == nil {
+		return "", false
+	}
+
+	name, ok := f[i][normalizeEnv(key)]
+	if !ok || name == key {
+		return "", false
+	}
+
+	return s.Lookup(name)
+}
+
+// normalizeEnv upper-cases name and turns each - into _, e.g. myapp-db-host into MYAPP_DB_HOST
+func normalizeEnv(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
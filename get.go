@@ -0,0 +1,208 @@
+package ruadan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	// ErrNoField is returned when a Configuration has no field with the name asked for
+	ErrNoField = errors.New("no such field")
+	// ErrFieldType is returned when a field of a Configuration can't be read as the type asked for
+	ErrFieldType = errors.New("field has a different type")
+)
+
+// Get reads the field called name from a Configuration built by BuildConfig as a T, e.g.
+// ruadan.Get[int64](&cfg, "Port"). Numbers can be read as any other number type, such as an int64 field as an int,
+// as long as the value fits: 300 read as an int8 or 1.5 read as an int is an ErrFieldType error
+func Get[T any](c *Configuration, name string) (T, error) {
+	var val T
+	field, err := c.field(name)
+	if err != nil {
+		return val, err
+	}
+
+	t := reflect.TypeOf(&val).Elem()
+	switch {
+	case field.Type().AssignableTo(t):
+	case isNumber(field) && isNumber(reflect.Zero(t)):
+		if field, err = convertNumber(field, t); err != nil {
+			return val, fmt.Errorf("%s: %w", name, err)
+		}
+	default:
+		return val, fmt.Errorf("%s: %w: %s is not %s", name, ErrFieldType, field.Type(), t)
+	}
+
+	reflect.ValueOf(&val).Elem().Set(field)
+	return val, nil
+}
+
+// Unmarshal copies the fields of a Configuration built by BuildConfig into dst, which must be a struct pointer, so the
+// rest of a program can use a typed struct. A field of dst is matched by its name, then by its envconfig: tag or the
+// env name its own name would have, e.g. APIPort matches the field built with OptionENVName("API_PORT"). Numbers are
+// converted the same as Get, fields of dst with no match are left as they are
+func (c *Configuration) Unmarshal(dst interface{}) error {
+	src, err := c.value()
+	if err != nil {
+		return err
+	}
+
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Ptr || d.IsNil() || d.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+	d = d.Elem()
+
+	byEnv := map[string]int{}
+	for i := 0; i < src.NumField(); i++ {
+		if env := src.Type().Field(i).Tag.Get("envconfig"); env != "" {
+			byEnv[env] = i
+		}
+	}
+
+	var errs []error
+	for i := 0; i < d.NumField(); i++ {
+		ft := d.Type().Field(i)
+		if ft.PkgPath != "" {
+			continue
+		}
+
+		name := ft.Name
+		field := src.FieldByName(name)
+		if !field.IsValid() {
+			env := envName(ft.Tag)
+			if env == "" {
+				env = envify(ft.Name)
+			}
+			j, ok := byEnv[env]
+			if !ok {
+				continue
+			}
+			field = src.Field(j)
+			name = src.Type().Field(j).Name
+		}
+
+		switch {
+		case field.Type().AssignableTo(ft.Type):
+		case isNumber(field) && isNumber(d.Field(i)):
+			if field, err = convertNumber(field, ft.Type); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", ft.Name, err))
+				continue
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: %w: %s is not %s", ft.Name, ErrFieldType, field.Type(), ft.Type))
+			continue
+		}
+
+		d.Field(i).Set(field)
+		c.access.mark(name)
+	}
+
+	return joinErrors(errs)
+}
+
+// convertNumber converts the number v to t, returning ErrFieldType when the value doesn't fit, such as 300 as an int8,
+// -1 as a uint or 1.5 as an int, the same as the flag parsers. A float only fails when it overflows, as a float32 flag
+// rounds its value the same way
+func convertNumber(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	c := v.Convert(t)
+	var fits bool
+	switch {
+	case c.Kind() == reflect.Float32 || c.Kind() == reflect.Float64:
+		fits = !math.IsInf(c.Float(), 0) || math.IsInf(toFloat(v), 0)
+	case isInt(v) && isUint(c):
+		fits = v.Int() >= 0 && c.Convert(v.Type()).Int() == v.Int()
+	case isUint(v) && isInt(c):
+		fits = c.Int() >= 0 && c.Convert(v.Type()).Uint() == v.Uint()
+	case isInt(v):
+		fits = c.Convert(v.Type()).Int() == v.Int()
+	case isUint(v):
+		fits = c.Convert(v.Type()).Uint() == v.Uint()
+	default:
+		fits = c.Convert(v.Type()).Float() == v.Float()
+	}
+
+	if !fits {
+		return reflect.Value{}, fmt.Errorf("%w: %v doesn't fit in %s", ErrFieldType, v.Interface(), t)
+	}
+
+	return c, nil
+}
+
+// Fields returns the names of the fields of a Configuration built by BuildConfig, in the order they were built, for
+// consumers that list a config without knowing its fields ahead of time
+func (c *Configuration) Fields() []string {
+	v, err := c.value()
+	if err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if ft := v.Type().Field(i); ft.PkgPath == "" {
+			names = append(names, ft.Name)
+		}
+	}
+
+	return names
+}
+
+// GetAll reads the fields called names from a Configuration built by BuildConfig, keyed by name, or every field when
+// no names are given, e.g. to render a template or an admin page. Names with no field are returned as ErrNoField
+// errors together, and the map still holds the rest
+func (c *Configuration) GetAll(names ...string) (map[string]interface{}, error) {
+	if len(names) == 0 {
+		if _, err := c.value(); err != nil {
+			return nil, err
+		}
+		names = c.Fields()
+	}
+
+	values := make(map[string]interface{}, len(names))
+	var errs []error
+	for _, name := range names {
+		field, err := c.field(name)
+		if err != nil {
+			if errors.Is(err, ErrInvalidConfig) {
+				return nil, err
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if !field.CanInterface() {
+			errs = append(errs, fmt.Errorf("%s: %w", name, ErrNoField))
+			continue
+		}
+		values[name] = field.Interface()
+	}
+
+	return values, joinErrors(errs)
+}
+
+// value returns the struct built by BuildConfig, or ErrInvalidConfig
+func (c *Configuration) value() (reflect.Value, error) {
+	v := reflect.ValueOf(c.Config)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, ErrInvalidConfig
+	}
+
+	return v.Elem(), nil
+}
+
+// field returns the field called name, or ErrNoField
+func (c *Configuration) field(name string) (reflect.Value, error) {
+	v, err := c.value()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	field := v.FieldByName(name)
+	if !field.IsValid() {
+		return reflect.Value{}, fmt.Errorf("%s: %w", name, ErrNoField)
+	}
+	c.access.mark(name)
+
+	return field, nil
+}
@@ -1,3 +1,3 @@
 module github.com/bit-cmdr/ruadan
 
-go 1.14
+go 1.18
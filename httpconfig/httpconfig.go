@@ -0,0 +1,110 @@
+// Package httpconfig hands a ruadan config struct to net/http handlers through the request context, with values
+// overridden per route and, for the fields allowed, per request by headers. Each route reads its own copy of the
+// config, so the timeout of one endpoint can be raised without touching the rest:
+//
+//	users, err := httpconfig.Route(ctx, &cfg, "USERS")
+//	mux.Handle("/users", users(usersHandler))
+//
+//	func usersHandler(w http.ResponseWriter, r *http.Request) {
+//		cfg, _ := httpconfig.FromContext[config](r.Context())
+//		ctx, cancel := context.WithTimeout(r.Context(), cfg.Timeout)
+//		...
+//	}
+package httpconfig
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/bit-cmdr/ruadan"
+)
+
+// key is the context key of a config struct of type T, so configs of different types don't collide
+type key[T any] struct{}
+
+// NewContext returns a copy of ctx holding cfg
+func NewContext[T any](ctx context.Context, cfg *T) context.Context {
+	return context.WithValue(ctx, key[T]{}, cfg)
+}
+
+// FromContext returns the config of type T held by ctx, or false if it holds none. Handlers must treat it as read-only,
+// as the same struct is shared by every request to a route
+func FromContext[T any](ctx context.Context) (*T, bool) {
+	cfg, ok := ctx.Value(key[T]{}).(*T)
+	return cfg, ok
+}
+
+// Handler returns middleware that puts cfg in the context of every request
+func Handler[T any](cfg *T) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), cfg)))
+		})
+	}
+}
+
+// Route loads a copy of cfg for a single route and returns middleware that puts it in the context of the route's
+// requests. The copy starts from the values cfg already holds, and is read with ruadan under prefix from the sources
+// and files set with options, so with the prefix USERS a Timeout field is overridden for the route by USERS_TIMEOUT.
+// The cli isn't read, as its flags belong to cfg
+func Route[T any](ctx context.Context, cfg *T, prefix string, options ...ruadan.LoadOptions) (
+	func(http.Handler) http.Handler,
+	error,
+) {
+	route := new(T)
+	*route = *cfg
+
+	options = append(options, ruadan.WithPrefix(prefix))
+	if err := load(ctx, route, options); err != nil {
+		return nil, err
+	}
+
+	return Handler(route), nil
+}
+
+// Headers returns middleware that overrides fields of the config in the request context with the request's headers,
+// for fields that are safe to change per request, such as a timeout a client may lower. Each field is named by its env
+// without any WithPrefix, and read from the header of the env after prefix, e.g. X-Config-TIMEOUT for TIMEOUT with the
+// prefix X-Config-. Requests without any of the headers are passed on as they are, and a header that doesn't parse is
+// answered with 400 Bad Request. Headers must come after Handler or Route, as there is nothing to override without a
+// config
+func Headers[T any](prefix string, fields ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cfg, ok := FromContext[T](r.Context())
+			values := ruadan.MapSource{}
+			for _, field := range fields {
+				if v := r.Header.Get(prefix + field); v != "" {
+					values[field] = v
+				}
+			}
+			if !ok || len(values) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			override := new(T)
+			*override = *cfg
+			err := load(r.Context(), override, []ruadan.LoadOptions{ruadan.WithSources(values), ruadan.WithStrict()})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), override)))
+		})
+	}
+}
+
+// load reads cfg with options, never from the cli and without exiting or printing usage on an error
+func load(ctx context.Context, cfg interface{}, options []ruadan.LoadOptions) error {
+	options = append(options,
+		ruadan.WithArgs([]string{}),
+		ruadan.WithErrorHandling(flag.ContinueOnError),
+		ruadan.WithOutput(ioutil.Discard),
+	)
+
+	return ruadan.NewLoader(options...).Load(ctx, cfg)
+}
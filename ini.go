@@ -0,0 +1,67 @@
+package ruadan
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseINI reads an INI file into a flat map keyed by the dotted path of each value. Sections, including dotted ones
+// like [server.tls], map to nested structs and keys are matched case-insensitively. Lines starting with ; or # are
+// comments, values can be quoted, and a key repeated within a section is joined with commas so it can be read into a
+// slice field
+func parseINI(r io.Reader) (map[string]string, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	section := ""
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+			continue
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("ini: line %d: unterminated section header", i+1)
+			}
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep <= 0 {
+			return nil, fmt.Errorf("ini: line %d: expected key = value", i+1)
+		}
+
+		key := strings.ToLower(strings.TrimSpace(line[:sep]))
+		if section != "" {
+			key = section + "." + key
+		}
+
+		value := iniValue(strings.TrimSpace(line[sep+1:]))
+		if existing, ok := values[key]; ok {
+			value = existing + "," + value
+		}
+		values[key] = value
+	}
+
+	return values, nil
+}
+
+// iniValue unquotes a value, or drops an inline ; or # comment that follows a space from an unquoted one
+func iniValue(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+
+	for i := 1; i < len(s); i++ {
+		if (s[i] == ';' || s[i] == '#') && (s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+
+	return s
+}
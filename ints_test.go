@@ -0,0 +1,206 @@
+package ruadan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// intsConfig puts small fields next to each other, so a write of the wrong width shows up in a neighbour
+type intsConfig struct {
+	Before int8   `envconfig:"BEFORE"`
+	Int8   int8   `envconfig:"INT8"`
+	Int16  int16  `envconfig:"INT16"`
+	Int32  int32  `envconfig:"INT32"`
+	Int64  int64  `envconfig:"INT64"`
+	Uint8  uint8  `envconfig:"UINT8"`
+	Uint16 uint16 `envconfig:"UINT16"`
+	Uint32 uint32 `envconfig:"UINT32"`
+	Uint64 uint64 `envconfig:"UINT64"`
+	After  int8   `envconfig:"AFTER"`
+}
+
+func TestIntegerWidths(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  intsConfig
+		// overflow is a value one past the range of the field
+		overflow string
+	}{
+		{key: "INT8", value: "-128", want: intsConfig{Int8: -128}, overflow: "128"},
+		{key: "INT16", value: "32767", want: intsConfig{Int16: 32767}, overflow: "-32769"},
+		{key: "INT32", value: "-2147483648", want: intsConfig{Int32: -2147483648}, overflow: "2147483648"},
+		{
+			key:      "INT64",
+			value:    "9223372036854775807",
+			want:     intsConfig{Int64: 9223372036854775807},
+			overflow: "9223372036854775808",
+		},
+		{key: "UINT8", value: "255", want: intsConfig{Uint8: 255}, overflow: "256"},
+		{key: "UINT16", value: "65535", want: intsConfig{Uint16: 65535}, overflow: "65536"},
+		{key: "UINT32", value: "4294967295", want: intsConfig{Uint32: 4294967295}, overflow: "4294967296"},
+		{
+			key:      "UINT64",
+			value:    "18446744073709551615",
+			want:     intsConfig{Uint64: 18446744073709551615},
+			overflow: "18446744073709551616",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+" env", func(t *testing.T) {
+			cfg := intsConfig{}
+			if err := testLoad(&cfg, map[string]string{tt.key: tt.value}, nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tt.want {
+				t.Errorf("got %+v, want %+v", cfg, tt.want)
+			}
+		})
+
+		t.Run(tt.key+" cli", func(t *testing.T) {
+			cfg := intsConfig{}
+			if err := testLoad(&cfg, nil, []string{"-" + tt.key, tt.value}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tt.want {
+				t.Errorf("got %+v, want %+v", cfg, tt.want)
+			}
+		})
+
+		t.Run(tt.key+" env overflow", func(t *testing.T) {
+			cfg := intsConfig{}
+			err := testLoad(&cfg, map[string]string{tt.key: tt.overflow}, nil, nil, WithStrict())
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Key != tt.key || fe.Value != tt.overflow {
+				t.Fatalf("expected a FieldError for %s=%s, got %v", tt.key, tt.overflow, err)
+			}
+			if cfg != (intsConfig{}) {
+				t.Errorf("an overflow should leave the fields at zero, got %+v", cfg)
+			}
+		})
+
+		t.Run(tt.key+" cli overflow", func(t *testing.T) {
+			cfg := intsConfig{}
+			err := testLoad(&cfg, nil, []string{"-" + tt.key, tt.overflow}, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.overflow) {
+				t.Fatalf("expected an error for -%s %s, got %v", tt.key, tt.overflow, err)
+			}
+		})
+	}
+}
+
+func TestIntegerWidthsLeaveNeighbours(t *testing.T) {
+	cfg := intsConfig{Before: 1, After: 2}
+	args := []string{
+		"-INT8", "-1", "-INT16", "-1", "-INT32", "-1", "-INT64", "-1",
+		"-UINT8", "1", "-UINT16", "1", "-UINT32", "1", "-UINT64", "1",
+	}
+	if err := testLoad(&cfg, nil, args, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := intsConfig{
+		Before: 1, Int8: -1, Int16: -1, Int32: -1, Int64: -1,
+		Uint8: 1, Uint16: 1, Uint32: 1, Uint64: 1, After: 2,
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want %+v", cfg, want)
+	}
+}
+
+func TestPrintDefaultsTypes(t *testing.T) {
+	var cfg struct {
+		TestInt    int    `envcli:"testint"`
+		TestString string `envcli:"teststring"`
+		Port       int    `envcli:"port" default:"8080"`
+		Small      int8   `envcli:"small"`
+		Count      uint16 `envcli:"count" default:"3"`
+		Debug      bool   `envcli:"debug"`
+	}
+	l := NewLoader(
+		WithArgs(nil),
+		WithSources(MapSource(nil)),
+		WithErrorHandling(flag.ContinueOnError),
+		WithOutput(ioutil.Discard),
+	)
+	if err := l.Load(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	fs := l.FlagSet()
+	fs.SetOutput(&out)
+	fs.PrintDefaults()
+	usage := out.String()
+
+	wants := []string{"-testint int\n", "-teststring string\n", "-port int\n", "(default 8080)", "(default 3)"}
+	for _, want := range wants {
+		if !strings.Contains(usage, want) {
+			t.Errorf("PrintDefaults should contain %q:\n%s", want, usage)
+		}
+	}
+	for _, zero := range []string{"(default 0)", "(default \"\")", "(default false)"} {
+		if strings.Contains(usage, zero) {
+			t.Errorf("PrintDefaults shouldn't show a zero default %s:\n%s", zero, usage)
+		}
+	}
+}
+
+func TestUnsignedFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    intsConfig
+		wantErr bool
+	}{
+		{name: "uint8", args: []string{"-UINT8", "200"}, want: intsConfig{Uint8: 200}},
+		{name: "uint16", args: []string{"-UINT16=40000"}, want: intsConfig{Uint16: 40000}},
+		{name: "uint32", args: []string{"-UINT32", "3000000000"}, want: intsConfig{Uint32: 3000000000}},
+		{
+			name: "uint64",
+			args: []string{"-UINT64", "10000000000000000000"},
+			want: intsConfig{Uint64: 10000000000000000000},
+		},
+		{name: "hex", args: []string{"-UINT16", "0xff"}, want: intsConfig{Uint16: 255}},
+		{
+			name: "all at once",
+			args: []string{"-UINT8", "1", "-UINT16", "2", "-UINT32", "3", "-UINT64", "4"},
+			want: intsConfig{Uint8: 1, Uint16: 2, Uint32: 3, Uint64: 4},
+		},
+		{name: "uint8 out of range", args: []string{"-UINT8", "256"}, wantErr: true},
+		{name: "uint16 out of range", args: []string{"-UINT16", "65536"}, wantErr: true},
+		{name: "uint32 out of range", args: []string{"-UINT32", "4294967296"}, wantErr: true},
+		{name: "uint64 out of range", args: []string{"-UINT64", "18446744073709551616"}, wantErr: true},
+		{name: "uint8 negative", args: []string{"-UINT8", "-1"}, wantErr: true},
+		{name: "uint16 negative", args: []string{"-UINT16=-1"}, wantErr: true},
+		{name: "uint32 negative", args: []string{"-UINT32", "-5"}, wantErr: true},
+		{name: "uint64 negative", args: []string{"-UINT64", "-1"}, wantErr: true},
+		{name: "not a number", args: []string{"-UINT32", "abc"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := intsConfig{}
+			err := testLoad(&cfg, nil, tt.args, nil)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %v, got %+v", tt.args, cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tt.want {
+				t.Errorf("got %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package ruadan
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// parseJSON reads a JSON object into a flat map keyed by the lower-cased, dotted path of each value. Arrays of
+// scalars are joined with commas, any other array is kept as its raw JSON
+func parseJSON(r io.Reader) (map[string]string, error) {
+	var obj map[string]interface{}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	flattenJSON("", obj, values)
+	return values, nil
+}
+
+func flattenJSON(prefix string, obj map[string]interface{}, values map[string]string) {
+	for k, v := range obj {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			flattenJSON(key, val, values)
+		case []interface{}:
+			items := make([]string, 0, len(val))
+			for _, item := range val {
+				s, ok := jsonScalar(item)
+				if !ok {
+					raw, _ := json.Marshal(val)
+					items = []string{string(raw)}
+					break
+				}
+				items = append(items, s)
+			}
+			values[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			values[key], _ = jsonScalar(val)
+		}
+	}
+}
+
+func jsonScalar(v interface{}) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return val, true
+	case json.Number:
+		return val.String(), true
+	case bool:
+		if val {
+			return "true", true
+		}
+		return "false", true
+	default:
+		return "", false
+	}
+}
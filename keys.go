@@ -0,0 +1,98 @@
+package ruadan
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EncryptedPrefix marks an encrypted config value, followed by the ciphertext in standard base64, e.g. enc:AAECAw==
+const EncryptedPrefix = "enc:"
+
+// KeyProvider decrypts the ciphertext of encrypted config values, so the backend that holds the key can be swapped,
+// e.g. a StaticKey in tests and KMS, Vault transit or an age identity file in production. The sources/aws,
+// sources/vault and keys/age modules provide the others
+type KeyProvider interface {
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// KeyProviderFunc adapts a function to a KeyProvider
+type KeyProviderFunc func(ctx context.Context, ciphertext []byte) ([]byte, error)
+
+// Decrypt calls f(ctx, ciphertext)
+func (f KeyProviderFunc) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return f(ctx, ciphertext)
+}
+
+// Decrypter returns a Resolver that decrypts values starting with EncryptedPrefix with kp, whichever layer they came
+// from, leaving every other value alone. Add it with WithResolver
+func Decrypter(kp KeyProvider) Resolver {
+	return ResolverFunc(func(ctx context.Context, value string) (string, bool, error) {
+		if !strings.HasPrefix(value, EncryptedPrefix) {
+			return "", false, nil
+		}
+
+		ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, EncryptedPrefix))
+		if err != nil {
+			return "", false, fmt.Errorf("decrypt: invalid base64: %v", err)
+		}
+
+		plaintext, err := kp.Decrypt(ctx, ciphertext)
+		if err != nil {
+			return "", false, fmt.Errorf("decrypt: %v", err)
+		}
+
+		return string(plaintext), true, nil
+	})
+}
+
+// StaticKey is a KeyProvider holding an AES key of 16, 24 or 32 bytes. Ciphertext is the 12 byte nonce followed by
+// the value sealed with AES-GCM, as written by Encrypt
+type StaticKey []byte
+
+var _ KeyProvider = StaticKey(nil)
+
+// Decrypt opens ciphertext written by Encrypt
+func (k StaticKey) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, sealed, nil)
+}
+
+// Encrypt seals plaintext with a random nonce and returns it as a config value, starting with EncryptedPrefix
+func (k StaticKey) Encrypt(plaintext string) (string, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return EncryptedPrefix + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func (k StaticKey) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,51 @@
+// Package age decrypts ruadan config values with age identities, such as an identity file made by age-keygen. It lives
+// in its own module so the core ruadan module stays free of dependencies
+package age
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/bit-cmdr/ruadan"
+)
+
+// Identities is a ruadan.KeyProvider that decrypts age ciphertext, binary or armored, with any of its identities
+type Identities []age.Identity
+
+var _ ruadan.KeyProvider = Identities(nil)
+
+// IdentityFile reads the identities in the age identity file at path, one per line with # comments
+func IdentityFile(path string) (Identities, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	ids, err := age.ParseIdentities(f)
+	if err != nil {
+		return nil, fmt.Errorf("age: %s: %v", path, err)
+	}
+
+	return ids, nil
+}
+
+// Decrypt opens ciphertext with the first identity that matches one of its recipients
+func (ids Identities) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	var src io.Reader = bytes.NewReader(ciphertext)
+	if bytes.HasPrefix(ciphertext, []byte(armor.Header)) {
+		src = armor.NewReader(src)
+	}
+
+	r, err := age.Decrypt(src, ids...)
+	if err != nil {
+		return nil, fmt.Errorf("age: %v", err)
+	}
+
+	return io.ReadAll(r)
+}
@@ -0,0 +1,15 @@
+module github.com/bit-cmdr/ruadan/keys/age
+
+go 1.22
+
+require (
+	filippo.io/age v1.2.1
+	github.com/bit-cmdr/ruadan v0.0.0-00010101000000-000000000000
+)
+
+require (
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/sys v0.21.0 // indirect
+)
+
+replace github.com/bit-cmdr/ruadan => ../..
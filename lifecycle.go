@@ -0,0 +1,157 @@
+package ruadan
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrRemovedField is wrapped by the FieldError of each field tagged `lifecycle:"removed"` that is still set
+var ErrRemovedField = errors.New("option was removed")
+
+// Level is the severity of a Diagnostic
+type Level int
+
+const (
+	// LevelInfo is used for informational messages, such as a new optional field that has not been set
+	LevelInfo Level = iota
+	// LevelWarn is used for problems that don't stop the config from loading, such as a deprecated field being set
+	LevelWarn
+	// LevelError is used for problems that stop the config from loading, such as a removed field being set
+	LevelError
+)
+
+// String returns the lower-case name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// Diagnostic is a message about a field emitted while reading a config struct
+type Diagnostic struct {
+	Level   Level
+	Field   string
+	Message string
+}
+
+// String formats the Diagnostic as a single line
+func (d Diagnostic) String() string {
+	return d.Level.String() + ": " + d.Field + ": " + d.Message
+}
+
+// Lifecycle stages that can be set on a field with the lifecycle: tag. An optional version can follow the stage,
+// e.g. `lifecycle:"deprecated,v1.4"`
+const (
+	// LifecycleNew marks an optional field added recently; an info Diagnostic is emitted when it isn't set
+	LifecycleNew = "new"
+	// LifecycleDeprecated marks a field that will be removed; a warn Diagnostic is emitted when it is set
+	LifecycleDeprecated = "deprecated"
+	// LifecycleRemoved marks a field that is no longer read; an error Diagnostic is emitted and an error returned
+	// when it is still set
+	LifecycleRemoved = "removed"
+)
+
+func logDiagnostic(d Diagnostic) {
+	if d.Level >= LevelWarn {
+		log.Println("ruadan", d.String())
+	}
+}
+
+// checkLifecycle emits a Diagnostic for every field with a lifecycle: tag based on whether it was found in the cli,
+// env or file. A removed field that is still set is returned as a FieldError wrapping ErrRemovedField
+func checkLifecycle(
+	ctx context.Context,
+	fs *flag.FlagSet,
+	metas []fieldMeta,
+	srcs sources,
+	files configFiles,
+	report func(Diagnostic),
+) error {
+	if report == nil {
+		report = func(Diagnostic) {}
+	}
+
+	set := setFlagNames(fs)
+
+	errs := []error{}
+	for _, meta := range metas {
+		tag := meta.Tags.Get("lifecycle")
+		if tag == "" {
+			continue
+		}
+
+		parts := strings.SplitN(tag, ",", 2)
+		stage := strings.TrimSpace(parts[0])
+		version := ""
+		if len(parts) > 1 {
+			version = " in " + strings.TrimSpace(parts[1])
+		}
+
+		source := lifecycleSource(ctx, meta, set, srcs, files)
+		switch {
+		case stage == LifecycleNew && source == "":
+			report(Diagnostic{
+				Level:   LevelInfo,
+				Field:   meta.Name,
+				Message: "new option" + version + " is not set, " + tagDesc(meta),
+			})
+		case stage == LifecycleDeprecated && source != "":
+			report(Diagnostic{
+				Level:   LevelWarn,
+				Field:   meta.Name,
+				Message: "option was deprecated" + version + " but is still set by " + source,
+			})
+		case stage == LifecycleRemoved && source != "":
+			report(Diagnostic{
+				Level:   LevelError,
+				Field:   meta.Name,
+				Message: "option was removed" + version + " but is still set by " + source,
+			})
+			err := fmt.Errorf("%w%s but is still set by %s", ErrRemovedField, version, source)
+			errs = append(errs, newFieldError(meta, "", err))
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+func lifecycleSource(
+	ctx context.Context,
+	meta fieldMeta,
+	set map[string]bool,
+	srcs sources,
+	files configFiles,
+) string {
+	if set[tagCLI(meta)] {
+		return "flag " + tagCLI(meta)
+	}
+
+	key := tagENV(meta)
+	src, _, ok := srcs.lookupField(ctx, key, meta.Tags)
+	if !ok {
+		src, key, _, ok = srcs.lookupAliases(ctx, append(envAliases(meta), renamedMetas(meta)...))
+	}
+	if ok {
+		if _, env := src.(EnvSource); env {
+			return "env " + key
+		}
+		return "source " + key
+	}
+
+	if file, _, ok := files.lookup(meta); ok {
+		return "file " + file.path
+	}
+
+	return ""
+}
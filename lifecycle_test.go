@@ -0,0 +1,45 @@
+package ruadan
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRemovedFields(t *testing.T) {
+	var cfg struct {
+		Host     string `envconfig:"HOST"`
+		LegacyDB string `envconfig:"LEGACY_DB" lifecycle:"removed,v2.0"`
+		OldPort  int    `envconfig:"OLD_PORT" lifecycle:"removed"`
+	}
+
+	quiet := WithDiagnostics(func(Diagnostic) {})
+	if err := testLoad(&cfg, map[string]string{"HOST": "a"}, nil, nil, quiet); err != nil {
+		t.Fatalf("removed fields that aren't set shouldn't fail, got %v", err)
+	}
+
+	err := testLoad(&cfg, map[string]string{"LEGACY_DB": "pg"}, []string{"-OLD_PORT", "1"}, nil, quiet)
+	if !errors.Is(err, ErrRemovedField) {
+		t.Fatalf("expected ErrRemovedField, got %v", err)
+	}
+
+	var le *LoadError
+	if !errors.As(err, &le) || len(le.Errors) != 2 {
+		t.Fatalf("expected a LoadError with one error per removed field, got %v", err)
+	}
+	want := map[string]string{
+		"LEGACY_DB": "LegacyDB (LEGACY_DB): option was removed in v2.0 but is still set by source LEGACY_DB",
+		"OLD_PORT":  "OldPort (OLD_PORT): option was removed but is still set by flag OLD_PORT",
+	}
+	for _, e := range le.Errors {
+		var fe *FieldError
+		if !errors.As(e, &fe) {
+			t.Fatalf("expected a FieldError, got %T %v", e, e)
+		}
+		if fe.Kind() != KindRemovedField {
+			t.Errorf("%s: Kind() = %v, want %v", fe.Key, fe.Kind(), KindRemovedField)
+		}
+		if fe.Error() != want[fe.Key] {
+			t.Errorf("got %q, want %q", fe.Error(), want[fe.Key])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package ruadan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation, SD_LISTEN_FDS_START
+const listenFDsStart = 3
+
+// ListenerConfig is a nested struct for a socket a service listens on, e.g. `HTTP ListenerConfig`, read from
+// HTTP_ADDR, HTTP_NETWORK, HTTP_FD_NAME and HTTP_FD. Listen uses a socket passed in by systemd socket activation or
+// inherited from a parent process before opening Addr itself, so the same config works for plain, socket-activated
+// and graceful-restart deployments
+type ListenerConfig struct {
+	// Network is the network Addr is opened on, tcp, tcp4, tcp6 or unix
+	Network string `envconfig:"NETWORK" envcli:"network" json:"network" default:"tcp" clidesc:"network to listen on"`
+	// Addr is the address opened when no socket is passed in, e.g. :8080
+	Addr string `envconfig:"ADDR" envcli:"addr" json:"addr" clidesc:"address to listen on"`
+	// FDName picks the socket with this name in LISTEN_FDNAMES, set by FileDescriptorName= in a systemd .socket unit.
+	// Without it the first socket passed in is used
+	FDName string `envconfig:"FD_NAME" envcli:"fd-name" json:"fd_name" clidesc:"name of the socket to use from LISTEN_FDNAMES"`
+	// FD is an inherited file descriptor to listen on, e.g. one passed to a new process in exec.Cmd.ExtraFiles during
+	// a graceful restart. Zero opens Addr
+	FD int `envconfig:"FD" envcli:"fd" json:"fd" clidesc:"inherited file descriptor to listen on"`
+}
+
+// Listen returns the listener for the config. A socket passed in by systemd, with LISTEN_PID set to this process, is
+// used first, then an inherited FD, and only when there is neither is Addr opened
+func (l ListenerConfig) Listen(ctx context.Context) (net.Listener, error) {
+	fd, name, err := l.activated()
+	switch {
+	case err != nil:
+		return nil, err
+	case fd > 0:
+		return fileListener(fd, name)
+	case l.FD > 0:
+		return fileListener(l.FD, "fd "+strconv.Itoa(l.FD))
+	case l.Addr == "":
+		return nil, errors.New("listener: no socket was passed in and no address is set")
+	}
+
+	network := l.Network
+	if network == "" {
+		network = "tcp"
+	}
+
+	var lc net.ListenConfig
+	return lc.Listen(ctx, network, l.Addr)
+}
+
+// Activated reports whether Listen will use a socket passed in by systemd socket activation
+func (l ListenerConfig) Activated() bool {
+	fd, _, err := l.activated()
+	return err == nil && fd > 0
+}
+
+// activated returns the file descriptor passed in by systemd for the config, or 0 if there isn't one. LISTEN_FDS is
+// ignored unless LISTEN_PID names this process, so a child doesn't take sockets meant for its parent
+func (l ListenerConfig) activated() (int, string, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return 0, "", nil
+	}
+
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return 0, "", nil
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	if l.FDName == "" {
+		return listenFDsStart, "LISTEN_FDS", nil
+	}
+
+	for i := 0; i < n && i < len(names); i++ {
+		if names[i] == l.FDName {
+			return listenFDsStart + i, "LISTEN_FDNAMES " + l.FDName, nil
+		}
+	}
+
+	return 0, "", fmt.Errorf("listener: no socket named %q in LISTEN_FDNAMES", l.FDName)
+}
+
+// fileListener creates a net.Listener from an open file descriptor. The listener holds its own copy of the descriptor,
+// so the original is closed
+func fileListener(fd int, name string) (net.Listener, error) {
+	f := os.NewFile(uintptr(fd), name)
+	if f == nil {
+		return nil, fmt.Errorf("listener: %s: invalid file descriptor %d", name, fd)
+	}
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("listener: %s: %v", name, err)
+	}
+
+	return ln, nil
+}
+
+// ListenerFile returns a copy of the file descriptor behind ln, to pass to a new process in exec.Cmd.ExtraFiles during
+// a graceful restart. The file at index i of ExtraFiles is descriptor 3+i in the new process, which is the FD to set in
+// its env
+func ListenerFile(ln net.Listener) (*os.File, error) {
+	f, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, fmt.Errorf("listener: %T has no file descriptor", ln)
+	}
+
+	return f.File()
+}
@@ -0,0 +1,398 @@
+package ruadan
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadOption holds the settings used by a Loader while reading a config struct
+type LoadOption struct {
+	args        []string
+	argFiles    bool
+	strict      bool
+	handling    flag.ErrorHandling
+	output      io.Writer
+	name        string
+	usage       string
+	examples    []string
+	sources     sources
+	files       configFiles
+	fsys        fs.FS
+	precedence  []Layer
+	prefix      string
+	configPath  string
+	dotenv      []string
+	diagnostics func(Diagnostic)
+	resolvers   []Resolver
+	validator   func(cfg interface{}) error
+	// unknownEnv is the prefix of envs reported when no field reads them, at unknownLevel
+	unknownEnv   string
+	unknownLevel Level
+	fuzzyEnv     bool
+	expand       bool
+	templates    bool
+	secretFiles  bool
+	// candidate is read in place of the config file by DiffFile
+	candidate string
+	defaults  *configFile
+	// origins records the layer each value was found in, keyed by env name, while loading
+	origins map[string]origin
+	// secrets holds the files read for WithSecretFiles, keyed by env name, while loading
+	secrets map[string]secretFile
+}
+
+// LoadOptions function used to change how a Loader reads a config struct
+type LoadOptions func(*LoadOption)
+
+// Loader reads config structs from the cli, sources, files and defaults. It is configured once with LoadOptions and
+// can be used to load any number of structs, including from several goroutines at once
+type Loader struct {
+	opt *LoadOption
+
+	mu      sync.Mutex
+	fs      *flag.FlagSet
+	sources []SourceInfo
+	lock    *Lock
+	// initial is a copy of the last struct loaded as it was passed in, so DiffFile starts from the same values
+	initial reflect.Value
+	origins []FieldOrigin
+}
+
+// NewLoader creates a Loader with the given options. Without options it reads os.Args[1:] and env, the same as
+// GetConfigFlagSet
+func NewLoader(options ...LoadOptions) *Loader {
+	return &Loader{opt: newLoadOption(options...)}
+}
+
+// WithArgs sets the cli args to parse, by default os.Args[1:]
+func WithArgs(args []string) LoadOptions {
+	return func(o *LoadOption) { o.args = args }
+}
+
+// WithConfigFile reads the YAML, JSON, TOML or INI file at path below every other file. Files ending in .json are read
+// as JSON, .toml as TOML, .ini as INI and everything else is read as YAML. Values from the file are matched with the
+// yaml:, json:, toml: or ini: tag, or the lower-cased field name if there is no tag, and nested structs are read from
+// nested mappings. The path can be overridden at launch with the -config flag or the CONFIG_FILE env
+func WithConfigFile(path string) LoadOptions {
+	return func(o *LoadOption) { o.configPath = path }
+}
+
+// WithFS reads config files from fsys instead of the operating system, e.g. an embed.FS or a fstest.MapFS. Paths are
+// cleaned and any leading / is dropped, so /etc/app/config.yaml is read as etc/app/config.yaml. This is the way to
+// read files when compiling to WebAssembly for a browser, where there is no file system to open
+func WithFS(fsys fs.FS) LoadOptions {
+	return func(o *LoadOption) { o.fsys = fsys }
+}
+
+// WithDiagnostics sets the function that receives the Diagnostic messages emitted while reading a config struct. By
+// default warnings and errors are written to the standard logger and info is dropped
+func WithDiagnostics(fn func(Diagnostic)) LoadOptions {
+	return func(o *LoadOption) { o.diagnostics = fn }
+}
+
+// WithStrict returns a *FieldError naming the field, the env, the raw value and the type it should have when a bool,
+// number, duration or string field can't be parsed, e.g. PORT=abc. Without it such a value leaves the field at its
+// zero value. Other field types always return an error. Keys in config files that no field reads, such as a misspelt
+// one, are also returned, each as a *FieldError wrapping ErrUnknownKey
+func WithStrict() LoadOptions {
+	return func(o *LoadOption) { o.strict = true }
+}
+
+// WithErrorHandling sets what happens when the cli args can't be parsed, by default flag.ExitOnError. With
+// flag.ContinueOnError the error is returned by Load instead, including flag.ErrHelp for -h or -help, so tests can
+// check bad input without the process exiting
+func WithErrorHandling(h flag.ErrorHandling) LoadOptions {
+	return func(o *LoadOption) { o.handling = h }
+}
+
+// WithOutput sets where usage and parse errors are written, by default os.Stderr
+func WithOutput(w io.Writer) LoadOptions {
+	return func(o *LoadOption) { o.output = w }
+}
+
+// WithPrefix adds a prefix to the env name of every field, including nested ones, so a field with the env name PORT
+// is read from MYAPP_PORT when the prefix is MYAPP. Flag names are left as they are
+func WithPrefix(prefix string) LoadOptions {
+	return func(o *LoadOption) { o.prefix = envify(strings.TrimSuffix(prefix, "_")) }
+}
+
+func newLoadOption(options ...LoadOptions) *LoadOption {
+	opt := &LoadOption{
+		args:        defaultArgs(),
+		sources:     sources{EnvSource{}},
+		precedence:  defaultPrecedence,
+		handling:    flag.ExitOnError,
+		name:        "config",
+		diagnostics: logDiagnostic,
+	}
+	for _, o := range options {
+		o(opt)
+	}
+
+	return opt
+}
+
+// newFlagSet creates the flag.FlagSet a load registers its flags on
+func (opt *LoadOption) newFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(opt.name, opt.handling)
+	if opt.output != nil {
+		fs.SetOutput(opt.output)
+	}
+
+	return fs
+}
+
+// defaultArgs returns os.Args[1:], which is empty rather than a panic on WebAssembly hosts that start the program
+// without any args
+func defaultArgs() []string {
+	if len(os.Args) < 2 {
+		return nil
+	}
+
+	return os.Args[1:]
+}
+
+// FlagSet returns the flag.FlagSet built by the last call to Load, or nil if nothing has been loaded
+func (l *Loader) FlagSet() *flag.FlagSet {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.fs
+}
+
+// Load reads cfg, which must be a struct pointer, from every layer. It stops early with ctx.Err() if the context is
+// done before loading finishes
+func (l *Loader) Load(ctx context.Context, cfg interface{}) error {
+	return l.load(ctx, l.opt, cfg, nil)
+}
+
+// load reads cfg with opt, filling in report as it goes when it isn't nil
+func (l *Loader) load(ctx context.Context, opt *LoadOption, cfg interface{}, report *Report) error {
+	start := time.Now()
+	if report != nil {
+		defer func() { report.Duration = time.Since(start) }()
+	}
+
+	metas, err := reflectConfig(opt.prefix, cfg)
+	if err != nil {
+		return err
+	}
+	initial := reflect.New(reflect.TypeOf(cfg).Elem())
+	initial.Elem().Set(reflect.ValueOf(cfg).Elem())
+
+	args, err := expandArgs(opt)
+	if err != nil {
+		return err
+	}
+	phase := report.phase("args", start)
+
+	srcs, err := opt.sources.withDotEnv(opt.fsys, opt.dotenv)
+	if err != nil {
+		return err
+	}
+
+	// the sources are reported whether or not the load succeeds, as a failed load is when they are most useful
+	ctx, stats := withSourceStats(ctx, srcs)
+	if opt.fuzzyEnv {
+		ctx = withFuzzyEnv(ctx, srcs)
+	}
+	defer func() {
+		l.mu.Lock()
+		l.sources = stats.infos
+		l.mu.Unlock()
+		if report != nil {
+			report.Sources = append([]SourceInfo(nil), stats.infos...)
+		}
+	}()
+	phase = report.phase("sources", phase)
+
+	// every load reads its own copy of the files, so loads running at the same time don't share any values
+	files := make(configFiles, 0, len(opt.files)+2)
+	if opt.defaults != nil {
+		path := defaultsFilePath(opt.fsys, opt.defaults.path)
+		if opt.defaults.want == "" {
+			// an empty sum is most often -ldflags left out of the build, which must not skip the check
+			return fmt.Errorf("%s: %w: no sha256 given for the defaults file", path, ErrChecksum)
+		}
+		f := &configFile{path: path, format: opt.defaults.format, want: opt.defaults.want}
+		switch err = f.read(opt.fsys); {
+		case err == nil:
+			files = append(files, f)
+		case !errors.Is(err, fs.ErrNotExist):
+			return err
+		}
+	}
+	configIndex := len(files)
+	configPath := ""
+	if opt.configPath != "" {
+		configPath = configFilePath(ctx, args, opt.configPath, srcs)
+		files = append(files, &configFile{path: configPath, format: fileFormat(configPath)})
+	}
+	for _, file := range opt.files {
+		files = append(files, &configFile{path: file.path, format: file.format})
+	}
+	if opt.candidate != "" {
+		candidate := &configFile{path: opt.candidate, format: fileFormat(opt.candidate)}
+		if configPath != "" {
+			files[configIndex] = candidate
+		} else {
+			files = append(files, candidate)
+		}
+	}
+
+	// the defaults file has already been read, as one that is missing is skipped
+	for _, file := range files[configIndex:] {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		err = file.read(opt.fsys)
+		if err != nil {
+			return err
+		}
+	}
+
+	phase = report.phase("files", phase)
+
+	loadOpt := *opt
+	loadOpt.args = args
+	loadOpt.sources = srcs
+	loadOpt.files = files
+	loadOpt.origins = map[string]origin{}
+	loadOpt.secrets = map[string]secretFile{}
+
+	// problems with values are collected rather than returned one at a time, so they can all be fixed at once
+	failed := map[string]error{}
+	fs := opt.newFlagSet()
+	for _, meta := range metas {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		err = parseMeta(fs, meta, lookupMeta(ctx, &loadOpt, meta), opt.strict)
+		if err != nil {
+			failed[tagENV(meta)] = err
+		}
+	}
+	// a secret file that can't be read leaves its field unset, and is reported in place of it being missing
+	for key, err := range loadOpt.secretFileErrors(metas) {
+		failed[key] = err
+	}
+
+	if configPath != "" && fs.Lookup(ConfigFileFlag) == nil {
+		fs.String(ConfigFileFlag, configPath, "flag: "+ConfigFileFlag+" or env: "+ConfigFileEnv)
+	}
+	if configPath != "" && fs.Lookup(DiffConfigFlag) == nil {
+		fs.String(DiffConfigFlag, "", "print how the config would change with this file instead of running")
+	}
+	shortErr := registerShorts(fs, metas)
+	registerNegations(fs, metas)
+	registerRenamed(fs, metas)
+	opt.setUsage(fs, metaFlagInfo(metas))
+	phase = report.phase("lookup", phase)
+
+	err = fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	precedenceErr := applyPrecedence(ctx, fs, metas, &loadOpt)
+	decodeErr := decodeFlags(fs, metas)
+	mirrorErr := applyMirrors(ctx, fs, metas, srcs, files)
+
+	// the fields of a nested struct switched off by its Enabled or a selects: field are left zero and not checked at
+	// all, and a field whose value failed to parse is already reported, not again as missing
+	active, enabledErr := applyEnabled(metas)
+	phase = report.phase("parse", phase)
+
+	errs := []error{}
+	required := make([]fieldMeta, 0, len(active))
+	for _, meta := range active {
+		if err, ok := failed[tagENV(meta)]; ok {
+			errs = append(errs, err)
+			continue
+		}
+		required = append(required, meta)
+	}
+
+	if opt.strict {
+		errs = appendErr(errs, files.unknownKeys(metas))
+	}
+	errs = appendErr(errs, shortErr)
+	errs = appendErr(errs, precedenceErr)
+	errs = appendErr(errs, decodeErr)
+	errs = appendErr(errs, mirrorErr)
+	errs = appendErr(errs, enabledErr)
+	if opt.expand {
+		errs = appendErr(errs, applyExpand(ctx, active, srcs))
+	}
+	if opt.templates {
+		errs = appendErr(errs, applyTemplates(cfg, active))
+	}
+	resolvers := opt.resolvers
+	if opt.secretFiles {
+		resolvers = append([]Resolver{SecretFiles(opt.fsys)}, resolvers...)
+	}
+	errs = appendErr(errs, applyResolvers(ctx, active, resolvers))
+	phase = report.phase("resolve", phase)
+
+	errs = appendErr(errs, checkRequired(required))
+	errs = appendErr(errs, checkConstraints(active))
+	if opt.validator != nil {
+		errs = appendErr(errs, runValidator(cfg, active, opt.validator))
+	} else {
+		errs = appendErr(errs, checkRules(active))
+	}
+	errs = appendErr(errs, checkValidators(cfg, active))
+	errs = appendErr(errs, checkLifecycle(ctx, fs, active, srcs, files, report.warn(opt.diagnostics)))
+	checkRenamed(ctx, fs, active, srcs, report.warn(opt.diagnostics))
+	errs = appendErr(errs, checkUnknownEnv(srcs, opt, metas, report.warn(opt.diagnostics)))
+	report.phase("check", phase)
+
+	if opt.candidate == "" {
+		origins := explainFields(fs, metas, active, &loadOpt)
+		report.explain(origins)
+		l.mu.Lock()
+		l.origins = origins
+		l.mu.Unlock()
+	}
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	if err = joinErrors(errs); err != nil {
+		return err
+	}
+
+	lock := newLock(fs, active, srcs, files)
+	l.mu.Lock()
+	l.fs = fs
+	l.lock = lock
+	l.initial = initial
+	l.mu.Unlock()
+
+	if f := fs.Lookup(DiffConfigFlag); f != nil && f.Value.String() != "" && opt.candidate == "" {
+		return l.printDiff(ctx, fs, f.Value.String())
+	}
+
+	return ctx.Err()
+}
+
+// Build creates a struct from the ConfigurationOption fields with BuildConfig and loads it
+func (l *Loader) Build(ctx context.Context, options ...ConfigurationOption) (Configuration, error) {
+	cfg := BuildConfig(options...)
+	if err := l.Load(ctx, cfg.Config); err != nil {
+		return Configuration{}, err
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,33 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing/fstest"
+)
+
+// testLoad loads cfg from env, args and files alone, so a test never reads the real env, cli or filesystem
+func testLoad(
+	cfg interface{},
+	env map[string]string,
+	args []string,
+	files map[string]string,
+	options ...LoadOptions,
+) error {
+	fsys := fstest.MapFS{}
+	for path, data := range files {
+		fsys[strings.TrimPrefix(path, "/")] = &fstest.MapFile{Data: []byte(data)}
+	}
+
+	options = append([]LoadOptions{
+		WithArgs(args),
+		WithSources(MapSource(env)),
+		WithFS(fsys),
+		WithErrorHandling(flag.ContinueOnError),
+		WithOutput(ioutil.Discard),
+	}, options...)
+
+	return NewLoader(options...).Load(context.Background(), cfg)
+}
@@ -0,0 +1,155 @@
+package ruadan
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Lock is the resolved config of a load along with where it came from, written by Loader.WriteLock so a job can be run
+// again with exactly the same values by Loader.LoadFromLock
+type Lock struct {
+	// Created is when the config was loaded
+	Created time.Time `json:"created"`
+	// Version and Revision are the main.version and vcs.revision of the binary that loaded the config, if it was built
+	// with them
+	Version  string `json:"version,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	// Sources are the names of the sources that were checked, in order
+	Sources []string `json:"sources"`
+	// Files are the config files that were read, with the sha256 of their contents
+	Files []LockFile `json:"files,omitempty"`
+	// Values holds the value of every field by its env name, in the form the field would be read from env
+	Values map[string]string `json:"values"`
+
+	// envs maps the dotted path of every field to its env name, for Loader.Environ, and secrets holds the env names of
+	// the fields tagged secret:, so diffs don't print them
+	envs    map[string]string
+	secrets map[string]bool
+}
+
+// LockFile is a config file recorded in a Lock
+type LockFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// newLock records the values of the active fields once a load has finished, reading them back through their flags so
+// they are written the same way they are parsed
+func newLock(fs *flag.FlagSet, metas []fieldMeta, srcs sources, files configFiles) *Lock {
+	lock := &Lock{
+		Created: time.Now().UTC(),
+		Sources: make([]string, len(srcs)),
+		Values:  make(map[string]string, len(metas)),
+		envs:    make(map[string]string, len(metas)),
+		secrets: map[string]bool{},
+	}
+	lock.Version, _ = lookupBuildInfo("main.version")
+	lock.Revision, _ = lookupBuildInfo("vcs.revision")
+
+	for i, s := range srcs {
+		lock.Sources[i] = sourceName(s)
+	}
+
+	for _, f := range files {
+		lock.Files = append(lock.Files, LockFile{Path: f.path, SHA256: f.sum})
+	}
+
+	for _, meta := range metas {
+		if fl := fs.Lookup(tagCLI(meta)); fl != nil {
+			v := fl.Value
+			if s, ok := v.(*secretValue); ok {
+				v = s.Value
+			}
+			lock.Values[tagENV(meta)] = v.String()
+			lock.envs[fieldPath(meta)] = tagENV(meta)
+			if isSecret(meta) {
+				lock.secrets[tagENV(meta)] = true
+			}
+		}
+	}
+
+	return lock
+}
+
+// Lock returns the Lock of the last call to Load, or nil if nothing has been loaded
+func (l *Loader) Lock() *Lock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lock
+}
+
+// WriteLock writes the Lock of the last call to Load to path as JSON, e.g. config.lock.json. The file holds every
+// resolved value, secrets included, so it is created readable by the owner only
+func (l *Loader) WriteLock(path string) error {
+	lock := l.Lock()
+	if lock == nil {
+		return errors.New("nothing has been loaded to lock")
+	}
+
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(b, '\n'), 0o600)
+}
+
+// ReadLock reads a Lock written by WriteLock from path, using the fs.FS set with WithFS if there is one
+func (l *Loader) ReadLock(path string) (*Lock, error) {
+	r, err := openFile(l.opt.fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	lock := &Lock{}
+	if err = json.NewDecoder(r).Decode(lock); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	return lock, nil
+}
+
+// LoadFromLock reads cfg from the values in the lock file at path instead of the cli, sources and files, so a job is
+// run again with exactly the configuration it had. Values are not resolved again, and defaults only fill fields the
+// lock doesn't have, e.g. ones added since it was written. A warning Diagnostic is emitted when the lock was written
+// by a different revision of the binary
+func (l *Loader) LoadFromLock(ctx context.Context, path string, cfg interface{}) error {
+	lock, err := l.ReadLock(path)
+	if err != nil {
+		return err
+	}
+
+	opt := *l.opt
+	opt.args = nil
+	opt.argFiles = false
+	opt.sources = sources{MapSource(lock.Values)}
+	opt.files = nil
+	opt.defaults = nil
+	opt.configPath = ""
+	opt.dotenv = nil
+	opt.resolvers = nil
+
+	if rev, _ := lookupBuildInfo("vcs.revision"); lock.Revision != "" && rev != lock.Revision && opt.diagnostics != nil {
+		opt.diagnostics(Diagnostic{
+			Level:   LevelWarn,
+			Field:   path,
+			Message: "locked by revision " + lock.Revision + ", running " + orUnknown(rev),
+		})
+	}
+
+	return l.load(ctx, &opt, cfg, nil)
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+
+	return s
+}
@@ -0,0 +1,75 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"fmt"
+)
+
+// applyMirrors keeps fields tagged `mirror:"Other"` in sync with the field they mirror, which must be a sibling in the
+// same struct with the same type. When the mirrored field is set by the cli, a source or a file it wins, otherwise a
+// set mirror field is copied over to it, so a legacy name keeps working while code reads the new one
+func applyMirrors(
+	ctx context.Context,
+	fs *flag.FlagSet,
+	metas []fieldMeta,
+	srcs sources,
+	files configFiles,
+) error {
+	var set map[string]bool
+	for _, meta := range metas {
+		name := meta.Tags.Get("mirror")
+		if name == "" {
+			continue
+		}
+
+		target, ok := findSibling(metas, meta, name)
+		if !ok {
+			return newFieldError(meta, "", fmt.Errorf("mirrored field %s not found", name))
+		}
+
+		if target.Field.Type() != meta.Field.Type() {
+			return newFieldError(meta, "", fmt.Errorf(
+				"mirrored field %s is a %s, not a %s", name, target.Field.Type(), meta.Field.Type(),
+			))
+		}
+
+		if set == nil {
+			set = setFlagNames(fs)
+		}
+
+		if lifecycleSource(ctx, target, set, srcs, files) == "" && lifecycleSource(ctx, meta, set, srcs, files) != "" {
+			target.Field.Set(meta.Field)
+			continue
+		}
+
+		meta.Field.Set(target.Field)
+	}
+
+	return nil
+}
+
+// findSibling finds the field called name in the same struct as meta
+func findSibling(metas []fieldMeta, meta fieldMeta, name string) (fieldMeta, bool) {
+	for _, m := range metas {
+		if m.Name == name && sameParents(m, meta) {
+			return m, true
+		}
+	}
+
+	return fieldMeta{}, false
+}
+
+func sameParents(a, b fieldMeta) bool {
+	if len(a.Parents) != len(b.Parents) {
+		return false
+	}
+
+	for i := range a.Parents {
+		if a.Parents[i].Name != b.Parents[i].Name {
+			return false
+		}
+	}
+
+	return true
+}
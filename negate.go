@@ -0,0 +1,72 @@
+package ruadan
+
+import (
+	"flag"
+	"reflect"
+	"strconv"
+)
+
+// negatePrefix is put before the name of a bool flag for the flag that sets it to false
+const negatePrefix = "no-"
+
+// negatedValue is the flag.Value of the -no- flag of a bool, which sets the bool to the opposite of its own value
+type negatedValue struct {
+	flag.Value
+	// name is the bool flag it negates
+	name string
+}
+
+func (n *negatedValue) Set(value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+
+	return n.Value.Set(strconv.FormatBool(!b))
+}
+
+// String is always false, as the -no- flag is only given to switch the bool off and has no value of its own, which
+// leaves its default out of flag.PrintDefaults
+func (n *negatedValue) String() string {
+	return "false"
+}
+
+func (n *negatedValue) aliasOf() string {
+	return n.name
+}
+
+// IsBoolFlag lets -no-VERBOSE be given bare, the same as -VERBOSE
+func (n *negatedValue) IsBoolFlag() bool {
+	return true
+}
+
+// registerNegations adds a -no- flag for every bool field, so a bool with a true default can be switched off with
+// -no-VERBOSE rather than -VERBOSE=false. A bool whose -no- name is already a flag gets none, and neither do other
+// flags given bare, such as a count
+func registerNegations(fs *flag.FlagSet, metas []fieldMeta) {
+	for _, meta := range metas {
+		f := fs.Lookup(tagCLI(meta))
+		if f == nil || fs.Lookup(negatePrefix+f.Name) != nil || derefField(meta.Field).Kind() != reflect.Bool {
+			continue
+		}
+
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			fs.Var(&negatedValue{Value: f.Value, name: f.Name}, negatePrefix+f.Name, "set "+f.Name+" to false")
+		}
+	}
+}
+
+// negatable reports whether the flag called name is a bool with a -no- flag
+func negatable(fs *flag.FlagSet, name string) bool {
+	f, n := fs.Lookup(name), fs.Lookup(negatePrefix+name)
+	if f == nil || n == nil {
+		return false
+	}
+
+	if _, count := f.Value.(*countValue); count {
+		return false
+	}
+
+	neg, ok := n.Value.(*negatedValue)
+	return ok && neg.name == name
+}
@@ -0,0 +1,74 @@
+package ruadan
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+type negateConfig struct {
+	Color bool `envconfig:"COLOR" default:"true"`
+	Debug bool `envconfig:"DEBUG" short:"d"`
+}
+
+func TestNegatedBool(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want negateConfig
+	}{
+		{name: "default", want: negateConfig{Color: true}},
+		{name: "bare", args: []string{"-no-COLOR"}, want: negateConfig{}},
+		{name: "double dash", args: []string{"--no-COLOR"}, want: negateConfig{}},
+		{name: "over env", env: map[string]string{"COLOR": "true"}, args: []string{"-no-COLOR"}, want: negateConfig{}},
+		{name: "false", args: []string{"-no-COLOR=false"}, want: negateConfig{Color: true}},
+		{name: "last wins", args: []string{"-no-COLOR", "-COLOR"}, want: negateConfig{Color: true}},
+		{name: "after short", args: []string{"-d", "-no-DEBUG"}, want: negateConfig{Color: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg negateConfig
+			if err := testLoad(&cfg, tt.env, tt.args, nil); err != nil {
+				t.Fatal(err)
+			}
+			if cfg != tt.want {
+				t.Errorf("loaded %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegatedBoolUsage(t *testing.T) {
+	var cfg negateConfig
+	var out bytes.Buffer
+	err := testLoad(&cfg, nil, []string{"-h"}, nil, WithOutput(&out))
+	if err == nil {
+		t.Fatal("expected -h to return flag.ErrHelp")
+	}
+
+	usage := out.String()
+	if !strings.Contains(usage, "-d, -[no-]DEBUG") {
+		t.Errorf("usage doesn't show -[no-]DEBUG:\n%s", usage)
+	}
+}
+
+func TestNegatedBoolDefaults(t *testing.T) {
+	var cfg negateConfig
+	l := NewLoader(WithArgs(nil), WithSources(MapSource(nil)))
+	if err := l.Load(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	fs := l.FlagSet()
+	fs.SetOutput(&out)
+	fs.PrintDefaults()
+	for _, name := range []string{"COLOR", "DEBUG"} {
+		if strings.Contains(out.String(), "set "+name+" to false (default") {
+			t.Errorf("-no-%s prints a default:\n%s", name, out.String())
+		}
+	}
+}
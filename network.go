@@ -0,0 +1,343 @@
+package ruadan
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ipType    = reflect.TypeOf(net.IP{})
+	ipNetType = reflect.TypeOf(net.IPNet{})
+	urlType   = reflect.TypeOf(url.URL{})
+)
+
+// netValue is the flag.Value for net.IP, net.IPNet and url.URL fields, so they are validated the same way whether
+// they are read from env, cli or a file
+type netValue struct {
+	field reflect.Value
+}
+
+func (n *netValue) Set(value string) error {
+	return parseValue(strings.TrimSpace(value), n.field)
+}
+
+func (n *netValue) String() string {
+	if n == nil || !n.field.IsValid() {
+		return ""
+	}
+
+	switch v := n.field.Addr().Interface().(type) {
+	case *net.IP:
+		if len(*v) == 0 {
+			return ""
+		}
+		return v.String()
+	case *net.IPNet:
+		if v.IP == nil {
+			return ""
+		}
+		return v.String()
+	case *url.URL:
+		return v.String()
+	default:
+		return ""
+	}
+}
+
+// parseURL sets a url.URL or *url.URL field, requiring a scheme so a host name alone isn't mistaken for a path. An
+// empty value clears the field
+func parseURL(v string, field reflect.Value) error {
+	u := &url.URL{}
+	if v = strings.TrimSpace(v); v != "" {
+		var err error
+		u, err = url.Parse(v)
+		if err != nil {
+			return err
+		}
+
+		if u.Scheme == "" {
+			return fmt.Errorf("invalid URL %q: missing scheme", v)
+		}
+	}
+
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.ValueOf(u))
+		return nil
+	}
+
+	field.Set(reflect.ValueOf(*u))
+	return nil
+}
+
+// parseCIDR sets a net.IPNet field from CIDR notation, e.g. 10.0.0.0/8
+func parseCIDR(v string, field reflect.Value) error {
+	_, n, err := net.ParseCIDR(strings.TrimSpace(v))
+	if err != nil {
+		return err
+	}
+
+	field.Set(reflect.ValueOf(*n))
+	return nil
+}
+
+// PortRange is an inclusive range of ports field type, set from values like 8000-8100 or a single port like 8080
+type PortRange struct {
+	Start uint16
+	End   uint16
+}
+
+// ParsePortRange parses a range of ports in the form start-end, or a single port
+func ParsePortRange(s string) (PortRange, error) {
+	parts := strings.SplitN(strings.TrimSpace(s), "-", 2)
+	start, err := parsePort(parts[0])
+	if err != nil {
+		return PortRange{}, fmt.Errorf("invalid port range %q: %v", s, err)
+	}
+
+	end := start
+	if len(parts) == 2 {
+		end, err = parsePort(parts[1])
+		if err != nil {
+			return PortRange{}, fmt.Errorf("invalid port range %q: %v", s, err)
+		}
+	}
+
+	if end < start {
+		return PortRange{}, fmt.Errorf("invalid port range %q: end is before start", s)
+	}
+
+	return PortRange{Start: start, End: end}, nil
+}
+
+// Set parses the value as a port range, used when reading from env or cli
+func (p *PortRange) Set(value string) error {
+	parsed, err := ParsePortRange(value)
+	if err != nil {
+		return err
+	}
+
+	*p = parsed
+	return nil
+}
+
+// String returns the range as start-end, or a single port when the range only holds one
+func (p *PortRange) String() string {
+	switch {
+	case p == nil || *p == (PortRange{}):
+		return ""
+	case p.Start == p.End:
+		return strconv.Itoa(int(p.Start))
+	default:
+		return strconv.Itoa(int(p.Start)) + "-" + strconv.Itoa(int(p.End))
+	}
+}
+
+// Contains reports whether port is inside the range
+func (p PortRange) Contains(port uint16) bool {
+	return port >= p.Start && port <= p.End
+}
+
+// Len returns the number of ports in the range
+func (p PortRange) Len() int {
+	if p == (PortRange{}) {
+		return 0
+	}
+
+	return int(p.End) - int(p.Start) + 1
+}
+
+func parsePort(s string) (uint16, error) {
+	port, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("invalid port %q", strings.TrimSpace(s))
+	}
+
+	return uint16(port), nil
+}
+
+// HostPort is a host and port field type validated with net.SplitHostPort, e.g. localhost:8080 or [::1]:8080. A
+// default port can be set with the port: tag, which is used when the value is only a host
+type HostPort struct {
+	Host string
+	Port string
+}
+
+// ParseHostPort parses a host:port pair, using defaultPort when s has no port. defaultPort can be empty to require
+// a port in s
+func ParseHostPort(s, defaultPort string) (HostPort, error) {
+	s = strings.TrimSpace(s)
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		if defaultPort == "" || strings.Count(strings.Trim(s, "[]"), ":") == 1 {
+			return HostPort{}, err
+		}
+		host, port = strings.Trim(s, "[]"), defaultPort
+	}
+
+	if host == "" {
+		return HostPort{}, fmt.Errorf("invalid address %q: missing host", s)
+	}
+
+	if p, err := strconv.ParseUint(port, 10, 16); err != nil || p == 0 {
+		return HostPort{}, fmt.Errorf("invalid address %q: invalid port %q", s, port)
+	}
+
+	return HostPort{Host: host, Port: port}, nil
+}
+
+// Set parses the value as a host:port pair, used when reading from env or cli
+func (h *HostPort) Set(value string) error {
+	parsed, err := ParseHostPort(value, "")
+	if err != nil {
+		return err
+	}
+
+	*h = parsed
+	return nil
+}
+
+// String returns the address in a form accepted by net.Dial
+func (h *HostPort) String() string {
+	if h == nil || *h == (HostPort{}) {
+		return ""
+	}
+
+	return net.JoinHostPort(h.Host, h.Port)
+}
+
+func (h *HostPort) flagValue(tags reflect.StructTag) flag.Value {
+	return &hostPortValue{hp: h, port: tags.Get("port")}
+}
+
+type hostPortValue struct {
+	hp   *HostPort
+	port string
+}
+
+func (v *hostPortValue) Set(value string) error {
+	parsed, err := ParseHostPort(value, v.port)
+	if err != nil {
+		return err
+	}
+
+	*v.hp = parsed
+	return nil
+}
+
+func (v *hostPortValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.hp.String()
+}
+
+// Endpoint is a single entry of Endpoints, with an optional scheme
+type Endpoint struct {
+	Scheme string
+	HostPort
+}
+
+// String returns the endpoint as scheme://host:port, or host:port if it has no scheme
+func (e Endpoint) String() string {
+	if e.Scheme == "" {
+		return e.HostPort.String()
+	}
+
+	return e.Scheme + "://" + e.HostPort.String()
+}
+
+// Endpoints is a field type for comma separated lists of addresses, e.g. host1:9092,tls://host2:9093. A default
+// scheme and port can be set with the scheme: and port: tags, which are used when an entry leaves them out
+type Endpoints []Endpoint
+
+// ParseEndpoints parses a comma separated list of endpoints, using the defaults for any scheme or port left out
+func ParseEndpoints(s, defaultScheme, defaultPort string) (Endpoints, error) {
+	endpoints := Endpoints{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		e := Endpoint{Scheme: defaultScheme}
+		if i := strings.Index(item, "://"); i >= 0 {
+			e.Scheme, item = item[:i], item[i+3:]
+		}
+
+		hp, err := ParseHostPort(item, defaultPort)
+		if err != nil {
+			return nil, err
+		}
+		e.HostPort = hp
+		endpoints = append(endpoints, e)
+	}
+
+	return endpoints, nil
+}
+
+// Set parses the value as a comma separated list of endpoints, used when reading from env or cli
+func (e *Endpoints) Set(value string) error {
+	parsed, err := ParseEndpoints(value, "", "")
+	if err != nil {
+		return err
+	}
+
+	*e = parsed
+	return nil
+}
+
+// String returns the endpoints as a comma separated list
+func (e *Endpoints) String() string {
+	if e == nil {
+		return ""
+	}
+
+	items := make([]string, len(*e))
+	for i, endpoint := range *e {
+		items[i] = endpoint.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// Addrs returns the host:port of every endpoint, dropping the schemes
+func (e Endpoints) Addrs() []string {
+	addrs := make([]string, len(e))
+	for i, endpoint := range e {
+		addrs[i] = endpoint.HostPort.String()
+	}
+	return addrs
+}
+
+func (e *Endpoints) flagValue(tags reflect.StructTag) flag.Value {
+	return &endpointsValue{endpoints: e, scheme: tags.Get("scheme"), port: tags.Get("port")}
+}
+
+type endpointsValue struct {
+	endpoints *Endpoints
+	scheme    string
+	port      string
+}
+
+func (v *endpointsValue) Set(value string) error {
+	parsed, err := ParseEndpoints(value, v.scheme, v.port)
+	if err != nil {
+		return err
+	}
+
+	*v.endpoints = parsed
+	return nil
+}
+
+func (v *endpointsValue) String() string {
+	if v == nil {
+		return ""
+	}
+
+	return v.endpoints.String()
+}
@@ -0,0 +1,75 @@
+package ruadan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Weighted is a name with a weight, read from name=weight. A []Weighted field is read from a comma separated list,
+// e.g. backend-a=3,backend-b=1, keeping the order it was given in. An entry without a weight has a weight of 1
+type Weighted struct {
+	Name   string
+	Weight int
+}
+
+// Set parses the value as name=weight, used when reading from env or cli
+func (w *Weighted) Set(value string) error {
+	name, weight := strings.TrimSpace(value), "1"
+	if i := strings.LastIndex(name, "="); i >= 0 {
+		name, weight = strings.TrimSpace(name[:i]), strings.TrimSpace(name[i+1:])
+	}
+
+	if name == "" {
+		return fmt.Errorf("invalid weighted entry %q: missing name", value)
+	}
+
+	n, err := strconv.Atoi(weight)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid weighted entry %q: weight must be a non-negative integer", value)
+	}
+
+	*w = Weighted{Name: name, Weight: n}
+	return nil
+}
+
+// String returns the entry as name=weight
+func (w *Weighted) String() string {
+	if w == nil || *w == (Weighted{}) {
+		return ""
+	}
+
+	return w.Name + "=" + strconv.Itoa(w.Weight)
+}
+
+// KV is a key and value, read from key=value. A []KV field is read from a comma separated list, e.g.
+// X-Team=core,X-Team=infra, keeping duplicates and the order it was given in where a map would lose them
+type KV struct {
+	Key   string
+	Value string
+}
+
+// Set parses the value as key=value, used when reading from env or cli
+func (kv *KV) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return fmt.Errorf("invalid key value pair %q: expected key=value", value)
+	}
+
+	key := strings.TrimSpace(value[:i])
+	if key == "" {
+		return fmt.Errorf("invalid key value pair %q: missing key", value)
+	}
+
+	*kv = KV{Key: key, Value: strings.TrimSpace(value[i+1:])}
+	return nil
+}
+
+// String returns the pair as key=value
+func (kv *KV) String() string {
+	if kv == nil || *kv == (KV{}) {
+		return ""
+	}
+
+	return kv.Key + "=" + kv.Value
+}
@@ -0,0 +1,379 @@
+package ruadan
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	pluginLookup = "lookup"
+	pluginCheck  = "check"
+
+	// codeMethodNotFound and codeServer are the JSON-RPC error codes for a method the plugin doesn't have and for an
+	// error from the plugin itself
+	codeMethodNotFound = -32601
+	codeServer         = -32000
+)
+
+type pluginRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int         `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type pluginResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int             `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *PluginError    `json:"error,omitempty"`
+}
+
+type lookupParams struct {
+	Key string `json:"key"`
+}
+
+type lookupResult struct {
+	Value string `json:"value"`
+	Found bool   `json:"found"`
+}
+
+// PluginError is an error returned by a plugin in a JSON-RPC response
+type PluginError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error returns the message of the plugin
+func (e *PluginError) Error() string {
+	return e.Message
+}
+
+// PluginSource is a Source served by a plugin, a separate binary, so a source for a proprietary store doesn't have to
+// be built into every program that reads it. A plugin reads JSON-RPC 2.0 requests from stdin and writes a response to
+// each on stdout, one JSON object per line, and exits when stdin is closed. Anything it writes to stderr is passed
+// through. The methods are:
+//
+//   - lookup, with params {"key": "DB_HOST"}, returns {"value": "db.internal", "found": true}
+//   - check returns null when the store the plugin reads can be reached, or an error. It is optional
+//
+// The plugin is started on the first lookup and kept running for later loads, and started again if it exits or a
+// lookup is cancelled while waiting on it. ServePlugin implements the protocol for a Source written in Go
+type PluginSource struct {
+	// Path is the plugin binary
+	Path string
+	// Args are passed to the plugin when it is started
+	Args []string
+	// Errors receives the errors of failed lookups, by default they are written to the standard logger
+	Errors func(error)
+
+	mu   sync.Mutex
+	proc *pluginProc
+	id   int
+}
+
+type pluginProc struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	out   *bufio.Reader
+}
+
+var (
+	_ ContextSource = (*PluginSource)(nil)
+	_ CheckedSource = (*PluginSource)(nil)
+)
+
+// NewPluginSource creates a PluginSource for the binary at path, started with args
+func NewPluginSource(path string, args ...string) *PluginSource {
+	return &PluginSource{Path: path, Args: args}
+}
+
+// DiscoverPlugins returns a PluginSource for every executable file in dir, sorted by name, for programs that let
+// teams drop their own sources into a plugins directory. Hidden files are skipped, and a dir that doesn't exist has no
+// plugins. On Windows only .exe files are used
+func DiscoverPlugins(dir string) ([]*PluginSource, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := []*PluginSource{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		if runtime.GOOS == "windows" {
+			if !strings.EqualFold(filepath.Ext(entry.Name()), ".exe") {
+				continue
+			}
+		} else if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+
+		plugins = append(plugins, NewPluginSource(filepath.Join(dir, entry.Name())))
+	}
+
+	return plugins, nil
+}
+
+// WithPlugins adds the plugins as sources, checked in order after env and any earlier sources, but before files
+func WithPlugins(plugins ...*PluginSource) LoadOptions {
+	return func(o *LoadOption) {
+		for _, p := range plugins {
+			o.sources = append(o.sources, p)
+		}
+	}
+}
+
+// Name returns plugin and the file name of the binary, the name the source is reported with by Loader.Sources
+func (p *PluginSource) Name() string {
+	return "plugin " + filepath.Base(p.Path)
+}
+
+// Lookup asks the plugin for key
+func (p *PluginSource) Lookup(key string) (string, bool) {
+	return p.LookupContext(context.Background(), key)
+}
+
+// LookupContext asks the plugin for key. An error is passed to Errors and reported as not found, so the field falls
+// through to the next layer
+func (p *PluginSource) LookupContext(ctx context.Context, key string) (string, bool) {
+	var res lookupResult
+	if err := p.call(ctx, pluginLookup, lookupParams{Key: key}, &res); err != nil {
+		p.report(fmt.Errorf("%s: lookup %s: %w", p.Name(), key, err))
+		return "", false
+	}
+
+	return res.Value, res.Found
+}
+
+// Check starts the plugin if it isn't running and calls its check method. A plugin without one is reachable once it
+// has started
+func (p *PluginSource) Check(ctx context.Context) error {
+	err := p.call(ctx, pluginCheck, nil, nil)
+	var pe *PluginError
+	if errors.As(err, &pe) && pe.Code == codeMethodNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// Close closes the stdin of the plugin and waits for it to exit, killing it if it is still running after 5 seconds
+func (p *PluginSource) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc == nil {
+		return nil
+	}
+
+	proc := p.proc
+	p.proc = nil
+	proc.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- proc.cmd.Wait() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		proc.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+func (p *PluginSource) report(err error) {
+	if p.Errors != nil {
+		p.Errors(err)
+		return
+	}
+
+	log.Println(err)
+}
+
+// call sends a request to the plugin, starting it when it isn't running, and reads the result into result
+func (p *PluginSource) call(ctx context.Context, method string, params, result interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.proc == nil {
+		if err := p.start(); err != nil {
+			return err
+		}
+	}
+
+	p.id++
+	req, err := json.Marshal(pluginRequest{JSONRPC: "2.0", ID: p.id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	proc := p.proc
+	if _, err = proc.stdin.Write(append(req, '\n')); err != nil {
+		p.kill()
+		return err
+	}
+
+	type line struct {
+		b   []byte
+		err error
+	}
+	done := make(chan line, 1)
+	go func() {
+		b, err := proc.out.ReadBytes('\n')
+		done <- line{b, err}
+	}()
+
+	var l line
+	select {
+	case <-ctx.Done():
+		// the response may still arrive, so the plugin is restarted rather than left out of step
+		p.kill()
+		return ctx.Err()
+	case l = <-done:
+	}
+
+	if l.err != nil {
+		p.kill()
+		return fmt.Errorf("plugin exited: %v", l.err)
+	}
+
+	var resp pluginResponse
+	err = json.Unmarshal(l.b, &resp)
+	if err != nil || resp.ID != p.id || resp.Result == nil && resp.Error == nil {
+		p.kill()
+		return fmt.Errorf("invalid response %q", strings.TrimSpace(string(l.b)))
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if result != nil && len(resp.Result) > 0 {
+		return json.Unmarshal(resp.Result, result)
+	}
+
+	return nil
+}
+
+func (p *PluginSource) start() error {
+	cmd := exec.Command(p.Path, p.Args...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+
+	p.proc = &pluginProc{cmd: cmd, stdin: stdin, out: bufio.NewReader(stdout)}
+	return nil
+}
+
+// kill stops a plugin that can't be trusted to answer the next request in step
+func (p *PluginSource) kill() {
+	p.proc.stdin.Close()
+	p.proc.cmd.Process.Kill()
+	p.proc.cmd.Wait()
+	p.proc = nil
+}
+
+// ServePlugin serves src as a plugin, reading requests from r and writing responses to w until r is closed. A plugin
+// binary written in Go only needs to call it from main:
+//
+//	func main() {
+//		if err := ruadan.ServePlugin(os.Stdin, os.Stdout, store.New()); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+func ServePlugin(r io.Reader, w io.Writer, src Source) error {
+	in := bufio.NewReader(r)
+	enc := json.NewEncoder(w)
+	ctx := context.Background()
+	for {
+		b, err := in.ReadBytes('\n')
+		if len(strings.TrimSpace(string(b))) > 0 {
+			var req struct {
+				ID     int             `json:"id"`
+				Method string          `json:"method"`
+				Params json.RawMessage `json:"params"`
+			}
+			resp := pluginResponse{JSONRPC: "2.0"}
+			if jerr := json.Unmarshal(b, &req); jerr != nil {
+				resp.Error = &PluginError{Code: codeServer, Message: "invalid request: " + jerr.Error()}
+			} else {
+				resp.ID = req.ID
+				resp.Result, resp.Error = servePlugin(ctx, src, req.Method, req.Params)
+			}
+
+			if eerr := enc.Encode(resp); eerr != nil {
+				return eerr
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// servePlugin calls method on src, returning the result or the error to respond with
+func servePlugin(
+	ctx context.Context, src Source, method string, params json.RawMessage,
+) (json.RawMessage, *PluginError) {
+	switch method {
+	case pluginLookup:
+		var p lookupParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &PluginError{Code: codeServer, Message: "invalid params: " + err.Error()}
+		}
+
+		val, ok := lookupSource(ctx, src, p.Key, "")
+		b, _ := json.Marshal(lookupResult{Value: val, Found: ok})
+		return b, nil
+	case pluginCheck:
+		cs, ok := src.(CheckedSource)
+		if !ok {
+			return nil, &PluginError{Code: codeMethodNotFound, Message: "method not found: " + method}
+		}
+		if err := cs.Check(ctx); err != nil {
+			return nil, &PluginError{Code: codeServer, Message: err.Error()}
+		}
+		return json.RawMessage("null"), nil
+	default:
+		return nil, &PluginError{Code: codeMethodNotFound, Message: "method not found: " + method}
+	}
+}
@@ -0,0 +1,218 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"runtime"
+)
+
+// Layer is one of the places a value can come from, used with WithPrecedence to order them
+type Layer int
+
+const (
+	// CLI is the layer of command line flags
+	CLI Layer = iota
+	// Env is the layer of sources, which is env unless changed with WithSources
+	Env
+	// File is the layer of config files
+	File
+	// Default is the value the struct was passed in with, or the default: tag if that is the zero value, used when no
+	// other layer sets the field. Tags like default_windows:, default_arm64: or default_linux_amd64: override default:
+	// on matching platforms, and buildinfo: and auto: tags override them all when they have a value
+	Default
+)
+
+// String returns the lower-case name of the layer
+func (l Layer) String() string {
+	switch l {
+	case CLI:
+		return "cli"
+	case Env:
+		return "env"
+	case File:
+		return "file"
+	default:
+		return "default"
+	}
+}
+
+var defaultPrecedence = []Layer{CLI, Env, File, Default}
+
+// WithPrecedence sets the order layers are checked in, highest first. Layers left out are not read at all, e.g.
+// WithPrecedence(Env, CLI, Default) lets env beat cli and ignores files. Default is always the last resort
+func WithPrecedence(layers ...Layer) LoadOptions {
+	return func(o *LoadOption) { o.precedence = append([]Layer{}, layers...) }
+}
+
+// lookupMeta builds the SourceFunc for a field, checking the env and file layers in order of precedence
+func lookupMeta(ctx context.Context, opt *LoadOption, meta fieldMeta) SourceFunc {
+	return func(key string) (string, bool) {
+		for _, layer := range opt.precedence {
+			if val, ok := lookupLayer(ctx, opt, layer, key, meta); ok {
+				return val, true
+			}
+		}
+
+		return lookupLayer(ctx, opt, Default, key, meta)
+	}
+}
+
+func lookupLayer(ctx context.Context, opt *LoadOption, layer Layer, key string, meta fieldMeta) (string, bool) {
+	switch layer {
+	case Env:
+		src, val, ok := opt.sources.lookupField(ctx, key, meta.Tags)
+		if ok {
+			opt.record(key, Env, sourceLabel(src, key, meta.Tags))
+		} else if indexedSlice(meta.Field.Type()) {
+			if val, ok = opt.sources.lookupIndexed(ctx, key); ok {
+				opt.record(key, Env, "numbered "+key+"_0 and up")
+			}
+		}
+		if !ok {
+			// aliases in the envconfig: tag come before the old names of a deprecated: tag
+			var alias string
+			aliases := append(envAliases(meta), renamedMetas(meta)...)
+			if src, alias, val, ok = opt.sources.lookupAliases(ctx, aliases); ok {
+				opt.record(key, Env, sourceLabel(src, alias, ""))
+			}
+		}
+		if !ok && opt.secretFiles {
+			var name string
+			if src, name, val, ok = opt.lookupSecretFile(ctx, key); ok {
+				opt.record(key, Env, sourceLabel(src, name, ""))
+			}
+		}
+		return val, ok
+	case File:
+		file, val, ok := opt.files.lookup(meta)
+		if ok {
+			opt.record(key, File, "file "+file.path)
+		}
+		return val, ok
+	case Default:
+		// a value already set on the struct wins over the default: tag
+		if !isZero(meta.Field) {
+			opt.record(key, Default, "value passed in")
+			return "", false
+		}
+		tag, val, ok := lookupDefaultTag(meta.Tags)
+		if ok {
+			opt.record(key, Default, tag)
+		}
+		return val, ok
+	default:
+		return "", false
+	}
+}
+
+// sourceLabel names the source a field was read from for FieldOrigin, e.g. env PORT or vault secret/data/app#token
+func sourceLabel(src Source, key string, tags reflect.StructTag) string {
+	if _, ok := src.(EnvSource); ok {
+		return "env " + key
+	}
+
+	if ts, ok := src.(TagSource); ok {
+		if tag, ok := tags.Lookup(ts.Tag()); ok {
+			return sourceName(src) + " " + tag
+		}
+	}
+
+	return sourceName(src) + " " + key
+}
+
+// defaultTags are checked in order for the default of a field, so the most specific platform wins, e.g.
+// default_linux_arm64 over default_linux over default_arm64 over default
+var defaultTags = []string{
+	"default_" + runtime.GOOS + "_" + runtime.GOARCH,
+	"default_" + runtime.GOOS,
+	"default_" + runtime.GOARCH,
+	"default",
+}
+
+// lookupDefault reads the default of a field from its buildinfo: tag when the binary has that value, its auto: tag,
+// or its default: tags
+func lookupDefault(tags reflect.StructTag) (string, bool) {
+	_, val, ok := lookupDefaultTag(tags)
+	return val, ok
+}
+
+// lookupDefaultTag works the same as lookupDefault, also naming the tag the default came from, e.g. default_linux tag
+// or buildinfo vcs.revision
+func lookupDefaultTag(tags reflect.StructTag) (string, string, bool) {
+	if key, ok := tags.Lookup("buildinfo"); ok {
+		if val, ok := lookupBuildInfo(key); ok {
+			return "buildinfo " + key, val, true
+		}
+	}
+
+	if key, ok := tags.Lookup("auto"); ok {
+		if val, ok := lookupAuto(key); ok {
+			return "auto " + key, val, true
+		}
+	}
+
+	for _, key := range defaultTags {
+		if val, ok := tags.Lookup(key); ok {
+			return key + " tag", val, true
+		}
+	}
+
+	return "", "", false
+}
+
+// layerRank returns the position of layer in the precedence, or -1 when it isn't read
+func layerRank(precedence []Layer, layer Layer) int {
+	for i, l := range precedence {
+		if l == layer {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// applyPrecedence runs after the flags are parsed and resets any flag that was set on the cli when a layer ranked
+// above cli has a value for it, or back to its default when cli isn't one of the layers
+func applyPrecedence(ctx context.Context, fs *flag.FlagSet, metas []fieldMeta, opt *LoadOption) error {
+	rank := layerRank(opt.precedence, CLI)
+
+	if rank == 0 {
+		return nil
+	}
+
+	above := opt.precedence
+	if rank > 0 {
+		above = opt.precedence[:rank]
+	}
+
+	set := setFlags(fs)
+	for _, meta := range metas {
+		f, ok := set[tagCLI(meta)]
+		if !ok {
+			continue
+		}
+
+		val, found := "", false
+		for _, layer := range above {
+			if val, found = lookupLayer(ctx, opt, layer, tagENV(meta), meta); found {
+				break
+			}
+		}
+
+		switch {
+		case found:
+		case rank < 0:
+			val = defValue(f)
+		default:
+			continue
+		}
+
+		startOver(f.Value)
+		if err := fs.Set(f.Name, val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
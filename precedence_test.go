@@ -0,0 +1,83 @@
+package ruadan
+
+import (
+	"reflect"
+	"testing"
+)
+
+type precedenceConfig struct {
+	Host  string   `envconfig:"HOST" default:"default" json:"host"`
+	Hosts []string `envconfig:"HOSTS" json:"hosts"`
+}
+
+func TestPrecedence(t *testing.T) {
+	file := map[string]string{"config.json": `{"host": "file", "hosts": ["file"]}`}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		files   map[string]string
+		options []LoadOptions
+		want    precedenceConfig
+	}{
+		{
+			name: "default",
+			want: precedenceConfig{Host: "default"},
+		},
+		{
+			name:  "file over default",
+			files: file,
+			want:  precedenceConfig{Host: "file", Hosts: []string{"file"}},
+		},
+		{
+			name:  "env over file",
+			env:   map[string]string{"HOST": "env", "HOSTS": "env"},
+			files: file,
+			want:  precedenceConfig{Host: "env", Hosts: []string{"env"}},
+		},
+		{
+			name:  "cli over env",
+			env:   map[string]string{"HOST": "env", "HOSTS": "env"},
+			args:  []string{"-host", "cli", "-hosts", "a", "-hosts", "b"},
+			files: file,
+			want:  precedenceConfig{Host: "cli", Hosts: []string{"a", "b"}},
+		},
+		{
+			name:    "env ranked over cli",
+			env:     map[string]string{"HOST": "env", "HOSTS": "env"},
+			args:    []string{"-host", "cli", "-hosts", "a"},
+			options: []LoadOptions{WithPrecedence(Env, CLI, Default)},
+			want:    precedenceConfig{Host: "env", Hosts: []string{"env"}},
+		},
+		{
+			name:    "files left out",
+			files:   file,
+			options: []LoadOptions{WithPrecedence(CLI, Env, Default)},
+			want:    precedenceConfig{Host: "default"},
+		},
+		{
+			name:    "cli left out",
+			env:     map[string]string{"HOSTS": "env"},
+			args:    []string{"-host", "cli", "-hosts", "a"},
+			options: []LoadOptions{WithPrecedence(Env, Default)},
+			want:    precedenceConfig{Host: "default", Hosts: []string{"env"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			if tt.files != nil {
+				options = append([]LoadOptions{WithConfigFile("config.json")}, options...)
+			}
+
+			var cfg precedenceConfig
+			if err := testLoad(&cfg, tt.env, tt.args, tt.files, options...); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("loaded %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
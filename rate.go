@@ -0,0 +1,118 @@
+package ruadan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate is a count per interval field type for rate limiters, e.g. 100/s, 50/m, 1000/1h or 10/500ms. The period can
+// be a unit (s, sec, second, m, min, minute, h, hr, hour, d, day) or any duration accepted by time.ParseDuration.
+// Full-width characters, such as ５０/m, are read as their ASCII equivalents
+type Rate struct {
+	N      int64
+	Period time.Duration
+}
+
+var rateUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+}
+
+// ParseRate parses a count per interval expression
+func ParseRate(s string) (Rate, error) {
+	value := strings.TrimSpace(narrow(s))
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return Rate{}, fmt.Errorf("invalid rate %q: expected count/period", s)
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 64)
+	if err != nil || n < 0 {
+		return Rate{}, fmt.Errorf("invalid rate %q: invalid count", s)
+	}
+
+	unit := strings.ToLower(strings.TrimSpace(parts[1]))
+	period, ok := rateUnits[unit]
+	if !ok && len(unit) > 2 {
+		period, ok = rateUnits[strings.TrimSuffix(unit, "s")]
+	}
+	if !ok {
+		if unit != "" && (unit[0] < '0' || unit[0] > '9') {
+			unit = "1" + unit
+		}
+		period, err = time.ParseDuration(unit)
+		if err != nil || period <= 0 {
+			return Rate{}, fmt.Errorf("invalid rate %q: invalid period", s)
+		}
+	}
+
+	return Rate{N: n, Period: period}, nil
+}
+
+// Set parses the value as a rate, used when reading from env or cli
+func (r *Rate) Set(value string) error {
+	parsed, err := ParseRate(value)
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+	return nil
+}
+
+// String returns the rate as count/period, using a unit when the period is exactly one
+func (r *Rate) String() string {
+	if r == nil || *r == (Rate{}) {
+		return ""
+	}
+
+	period := r.Period.String()
+	switch r.Period {
+	case time.Second:
+		period = "s"
+	case time.Minute:
+		period = "m"
+	case time.Hour:
+		period = "h"
+	case 24 * time.Hour:
+		period = "d"
+	}
+
+	return strconv.FormatInt(r.N, 10) + "/" + period
+}
+
+// PerSecond returns the rate as events per second
+func (r Rate) PerSecond() float64 {
+	if r.Period <= 0 {
+		return 0
+	}
+
+	return float64(r.N) / r.Period.Seconds()
+}
+
+// Interval returns the time between events when they are spread evenly over the period
+func (r Rate) Interval() time.Duration {
+	if r.N <= 0 {
+		return 0
+	}
+
+	return r.Period / time.Duration(r.N)
+}
+
+// narrow maps full-width forms of ASCII characters, such as ５ or ／, to their ASCII equivalents
+func narrow(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= '！' && r <= '～':
+			return r - '！' + '!'
+		case r == '　':
+			return ' '
+		default:
+			return r
+		}
+	}, s)
+}
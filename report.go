@@ -0,0 +1,118 @@
+package ruadan
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Report describes a single load, with how long it took, what each layer set and any warnings, as one value to log at
+// startup or add to a support bundle
+type Report struct {
+	// Duration is the time the whole load took, and Phases the time of each step in the order they ran
+	Duration time.Duration
+	Phases   []Phase
+	Sources  []SourceInfo
+	// Set counts the fields set by each layer. Fields nothing set and fields switched off aren't counted
+	Set map[Layer]int
+	// Warnings are the Diagnostics of LevelWarn and above emitted by the load. They are passed to WithDiagnostics too
+	Warnings []Diagnostic
+	// Fields is where the value of every field came from, the same as Explain
+	Fields []FieldOrigin
+}
+
+// Phase is a step of a load and the time it took. The steps are args, sources, files, lookup, parse, resolve and
+// check, and a load that fails stops at the step it failed in
+type Phase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// LoadReport reads cfg the same as Load and returns a Report of the load. The Report is returned even when the load
+// fails, covering the steps that ran, as that is when it is most useful
+func (l *Loader) LoadReport(ctx context.Context, cfg interface{}) (*Report, error) {
+	report := &Report{Set: map[Layer]int{}}
+	err := l.load(ctx, l.opt, cfg, report)
+	return report, err
+}
+
+// String formats the Report over several lines, starting with the total time and the time of each phase
+func (r *Report) String() string {
+	var b strings.Builder
+
+	phases := make([]string, len(r.Phases))
+	for i, p := range r.Phases {
+		phases[i] = p.Name + " " + p.Duration.String()
+	}
+	fmt.Fprintf(&b, "loaded in %s (%s)\n", r.Duration, strings.Join(phases, ", "))
+
+	set := []string{}
+	for _, layer := range defaultPrecedence {
+		if n := r.Set[layer]; n > 0 {
+			set = append(set, fmt.Sprintf("%d %s", n, layer))
+		}
+	}
+	if len(set) > 0 {
+		fmt.Fprintf(&b, "set: %s\n", strings.Join(set, ", "))
+	}
+
+	for _, s := range r.Sources {
+		if !s.Reachable {
+			fmt.Fprintf(&b, "source %s: unreachable: %v\n", s.Name, s.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "source %s: %d keys in %d lookups, %s\n", s.Name, s.Keys, s.Lookups, s.Duration)
+	}
+
+	for _, d := range r.Warnings {
+		b.WriteString(d.String() + "\n")
+	}
+
+	for _, o := range r.Fields {
+		b.WriteString(o.String() + "\n")
+	}
+
+	return b.String()
+}
+
+// phase records the time since start as the named phase and returns the time to start the next phase from. It does
+// nothing on a nil Report, so loads that aren't reported don't need to check
+func (r *Report) phase(name string, start time.Time) time.Time {
+	now := time.Now()
+	if r != nil {
+		r.Phases = append(r.Phases, Phase{Name: name, Duration: now.Sub(start)})
+	}
+
+	return now
+}
+
+// warn wraps fn so Diagnostics of LevelWarn and above are kept in the Report as well
+func (r *Report) warn(fn func(Diagnostic)) func(Diagnostic) {
+	if r == nil {
+		return fn
+	}
+
+	return func(d Diagnostic) {
+		if d.Level >= LevelWarn {
+			r.Warnings = append(r.Warnings, d)
+		}
+		if fn != nil {
+			fn(d)
+		}
+	}
+}
+
+// explain keeps the origins of a load in the Report and counts the fields set by each layer
+func (r *Report) explain(origins []FieldOrigin) {
+	if r == nil {
+		return
+	}
+
+	r.Fields = origins
+	for _, o := range origins {
+		if o.Source != "" && o.Source != "switched off" {
+			r.Set[o.Layer]++
+		}
+	}
+}
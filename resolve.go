@@ -0,0 +1,60 @@
+package ruadan
+
+import (
+	"context"
+	"reflect"
+)
+
+// Resolver replaces references in string values with what they point to, e.g. a secretsmanager:// reference with the
+// secret it names. Resolve reports false for values it doesn't handle, so they are left as they are
+type Resolver interface {
+	Resolve(ctx context.Context, value string) (string, bool, error)
+}
+
+// ResolverFunc adapts a function to a Resolver
+type ResolverFunc func(ctx context.Context, value string) (string, bool, error)
+
+// Resolve calls f(ctx, value)
+func (f ResolverFunc) Resolve(ctx context.Context, value string) (string, bool, error) {
+	return f(ctx, value)
+}
+
+// WithResolver adds a Resolver that runs on every string field once all of the layers are merged, whichever layer
+// the value came from. Resolvers run in the order they are added and the first one to handle a value wins
+func WithResolver(r Resolver) LoadOptions {
+	return func(o *LoadOption) { o.resolvers = append(o.resolvers, r) }
+}
+
+// applyResolvers resolves the value of every string field, returning a FieldError for each one that fails
+func applyResolvers(ctx context.Context, metas []fieldMeta, resolvers []Resolver) error {
+	if len(resolvers) == 0 {
+		return nil
+	}
+
+	errs := []error{}
+	for _, meta := range metas {
+		field := meta.Field
+		if field.Kind() == reflect.Ptr && !field.IsNil() {
+			field = field.Elem()
+		}
+
+		if field.Kind() != reflect.String || field.Len() == 0 {
+			continue
+		}
+
+		for _, r := range resolvers {
+			val, ok, err := r.Resolve(ctx, field.String())
+			if err != nil {
+				errs = append(errs, newFieldError(meta, field.String(), err))
+				break
+			}
+
+			if ok {
+				field.SetString(val)
+				break
+			}
+		}
+	}
+
+	return joinErrors(errs)
+}
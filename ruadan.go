@@ -1,22 +1,26 @@
 package ruadan
 
 import (
+	"context"
 	"encoding"
+	"encoding/json"
 	"errors"
 	"flag"
-	"os"
+	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 	"unicode"
-	"unsafe"
 )
 
 // ErrInvalidConfig is the default error message if you don't pass the cfg argument as a struct pointer to
 // GetConfigFlagSet
 var ErrInvalidConfig = errors.New("cfg must be a struct pointer")
 
+var durationType = reflect.TypeOf(time.Duration(0))
+
 // ConfigurationOption is the extensible struct used to build up a struct field that will be returned as
 // Configuration.Config
 type ConfigurationOption struct {
@@ -27,6 +31,10 @@ type ConfigurationOption struct {
 	usage        string
 	defaultValue interface{}
 	useCLI       bool
+	secret       bool
+	required     bool
+	defaultTag   string
+	validate     string
 }
 
 // Decoder interface to decode a string
@@ -45,32 +53,72 @@ type ConfigurationOptions func(*ConfigurationOption)
 // Configuration is returned by BuildConfig as an unknown struct to read valued from after initial creation
 type Configuration struct {
 	Config interface{}
+	// access records the fields read once TrackAccess is called
+	access *accessLog
 }
 
-// GetBool gets a boolean value from the key that matches the provided name in the Configuration
+// GetBool gets a boolean value from the key that matches the provided name in the Configuration. It returns false
+// when there is no such field or it isn't a bool, use GetBoolE to tell those apart
 func (c *Configuration) GetBool(name string) bool {
-	return reflect.ValueOf(c.Config).Elem().FieldByName(name).Bool()
+	v, _ := c.GetBoolE(name)
+	return v
+}
+
+// GetBoolE gets a boolean value from the key that matches the provided name in the Configuration, or ErrNoField or
+// ErrFieldType
+func (c *Configuration) GetBoolE(name string) (bool, error) {
+	return Get[bool](c, name)
 }
 
-// GetString gets a string value from the key that matches the provided name in the Configuration
+// GetString gets a string value from the key that matches the provided name in the Configuration. It returns "" when
+// there is no such field or it isn't a string, use GetStringE to tell those apart
 func (c *Configuration) GetString(name string) string {
-	return reflect.ValueOf(c.Config).Elem().FieldByName(name).String()
+	v, _ := c.GetStringE(name)
+	return v
+}
+
+// GetStringE gets a string value from the key that matches the provided name in the Configuration, or ErrNoField or
+// ErrFieldType
+func (c *Configuration) GetStringE(name string) (string, error) {
+	return Get[string](c, name)
 }
 
-// GetInt64 gets a int64 value from the key that matches the provided name in the Configuration
+// GetInt64 gets a int64 value from the key that matches the provided name in the Configuration. It returns 0 when
+// there is no such field or it isn't a number, use GetInt64E to tell those apart
 func (c *Configuration) GetInt64(name string) int64 {
-	return reflect.ValueOf(c.Config).Elem().FieldByName(name).Int()
+	v, _ := c.GetInt64E(name)
+	return v
 }
 
-// GetFloat64 gets a float64 value from the key that matches the provided name in the Configuration
+// GetInt64E gets a int64 value from the key that matches the provided name in the Configuration, or ErrNoField or
+// ErrFieldType
+func (c *Configuration) GetInt64E(name string) (int64, error) {
+	return Get[int64](c, name)
+}
+
+// GetFloat64 gets a float64 value from the key that matches the provided name in the Configuration. It returns 0 when
+// there is no such field or it isn't a number, use GetFloat64E to tell those apart
 func (c *Configuration) GetFloat64(name string) float64 {
-	return reflect.ValueOf(c.Config).Elem().FieldByName(name).Float()
+	v, _ := c.GetFloat64E(name)
+	return v
+}
+
+// GetFloat64E gets a float64 value from the key that matches the provided name in the Configuration, or ErrNoField or
+// ErrFieldType
+func (c *Configuration) GetFloat64E(name string) (float64, error) {
+	return Get[float64](c, name)
 }
 
 // GetComplex gets an interface value from the key that matches the provided name in the Configuration.
-// This assumes you know what you're asking for and how to cast the result
+// This assumes you know what you're asking for and how to cast the result. It returns nil when there is no such field
 func (c *Configuration) GetComplex(name string) interface{} {
-	return reflect.ValueOf(c.Config).Elem().FieldByName(name).Interface()
+	v, _ := c.GetComplexE(name)
+	return v
+}
+
+// GetComplexE gets an interface value from the key that matches the provided name in the Configuration, or ErrNoField
+func (c *Configuration) GetComplexE(name string) (interface{}, error) {
+	return Get[interface{}](c, name)
 }
 
 // OptionJSONName used to add a json: tag to a struct field
@@ -99,6 +147,26 @@ func OptionCLIUsage(usage string) ConfigurationOptions {
 	}
 }
 
+// OptionSecret used to add a secret:"true" tag to a struct field, so its value is redacted wherever it is printed
+func OptionSecret() ConfigurationOptions {
+	return func(o *ConfigurationOption) { o.secret = true }
+}
+
+// OptionDefault used to add a default: tag to a struct field, the value used when no flag, env or file sets it
+func OptionDefault(value string) ConfigurationOptions {
+	return func(o *ConfigurationOption) { o.defaultTag = value }
+}
+
+// OptionRequired used to add a required:"true" tag to a struct field, so loading fails while it is left unset
+func OptionRequired() ConfigurationOptions {
+	return func(o *ConfigurationOption) { o.required = true }
+}
+
+// OptionValidate used to add a validate: tag to a struct field with rules such as "min=1,max=65535"
+func OptionValidate(rules string) ConfigurationOptions {
+	return func(o *ConfigurationOption) { o.validate = rules }
+}
+
 // NewOptionInt creates a new int64 struct field with the given name and options. When considering the name, remember
 // Go's syntax of an upper-case first letter
 func NewOptionInt(name string, options ...ConfigurationOptions) ConfigurationOption {
@@ -134,56 +202,49 @@ func NewOptionComplex(name string, defaultValue interface{}, options ...Configur
 // it will use the envconfig: tag to find the matching environment variable and that can be overridden at launch with a
 // command line flag. The flag will be the same as the envconfig: if not specified, or can be changed with the
 // envcli: tag
-func GetConfigFlagSet(args []string, cfg interface{}) (*flag.FlagSet, error) {
-	metas, err := reflectConfig("", cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	fs := flag.NewFlagSet("config", flag.ExitOnError)
-	for _, meta := range metas {
-		err = parseMeta(fs, meta)
-		if err != nil {
-			return nil, err
-		}
-	}
+func GetConfigFlagSet(args []string, cfg interface{}, options ...LoadOptions) (*flag.FlagSet, error) {
+	return GetConfigFlagSetContext(context.Background(), args, cfg, options...)
+}
 
-	err = fs.Parse(args)
-	if err != nil {
+// GetConfigFlagSetContext works the same as GetConfigFlagSet, but stops with ctx.Err() once ctx is done. The context
+// is passed on to every ContextSource, so slow or remote sources can be cancelled or given a deadline
+func GetConfigFlagSetContext(
+	ctx context.Context,
+	args []string,
+	cfg interface{},
+	options ...LoadOptions,
+) (*flag.FlagSet, error) {
+	// copied so the caller's options are never appended to
+	l := NewLoader(append(append(make([]LoadOptions, 0, len(options)+1), options...), WithArgs(args))...)
+	if err := l.Load(ctx, cfg); err != nil {
 		return nil, err
 	}
 
-	return fs, nil
+	return l.FlagSet(), nil
+}
+
+// GetConfigFlagSetWithFile works the same as GetConfigFlagSet, but will first read the config file at path. See
+// WithConfigFile for how the file is read
+func GetConfigFlagSetWithFile(
+	args []string,
+	path string,
+	cfg interface{},
+	options ...LoadOptions,
+) (*flag.FlagSet, error) {
+	return GetConfigFlagSet(args, cfg, append([]LoadOptions{WithConfigFile(path)}, options...)...)
 }
 
 // BuildConfig takes a variable amount of ConfigurationOption arguments and uses them to build a struct. This allows
 // you to be very specific in how to build the struct if you don't want to have a struct at the top of your file and
-// want to build it as you go
+// want to build it as you go. Nothing is read or registered until the struct is passed to GetConfigFlagSet or a
+// Loader, so BuildConfig is safe to call from several goroutines
 func BuildConfig(options ...ConfigurationOption) Configuration {
-	fields := []reflect.StructField{}
+	return Configuration{Config: reflect.New(buildStruct(options...)).Interface()}
+}
+
+func buildStruct(options ...ConfigurationOption) reflect.Type {
+	fields := make([]reflect.StructField, 0, len(options))
 	for _, o := range options {
-		switch o.defaultValue.(type) {
-		case bool:
-			dv := lookupEnvOrBool(o.envName, o.defaultValue.(bool))
-			if o.useCLI {
-				flag.Bool(o.cliName, dv, o.usage)
-			}
-		case int64:
-			dv := lookupEnvOrInt64(o.envName, o.defaultValue.(int64))
-			if o.useCLI {
-				flag.Int64(o.cliName, dv, o.usage)
-			}
-		case float64:
-			dv := lookupEnvOrFloat64(o.envName, o.defaultValue.(float64))
-			if o.useCLI {
-				flag.Float64(o.cliName, dv, o.usage)
-			}
-		default:
-			dv := lookupEnvOrString(o.envName, o.defaultValue.(string))
-			if o.useCLI {
-				flag.String(o.cliName, dv, o.usage)
-			}
-		}
 		fields = append(fields, reflect.StructField{
 			Name: o.name,
 			Type: reflect.TypeOf(o.defaultValue),
@@ -191,8 +252,7 @@ func BuildConfig(options ...ConfigurationOption) Configuration {
 		})
 	}
 
-	obj := reflect.StructOf(fields)
-	return Configuration{Config: reflect.New(obj).Interface()}
+	return reflect.StructOf(fields)
 }
 
 func newOption(name string, dv interface{}, options ...ConfigurationOptions) ConfigurationOption {
@@ -219,7 +279,11 @@ func newOption(name string, dv interface{}, options ...ConfigurationOptions) Con
 	return *opt
 }
 
-func parseMeta(fs *flag.FlagSet, meta fieldMeta) error {
+// parseMeta registers the flag for a field. The value the field already holds is used as its default, so a struct
+// passed in with values set keeps them unless a flag, env or file sets something else. In strict mode a bool, number
+// or string value that doesn't parse is returned as a FieldError instead of leaving the field at its zero value. The
+// flag is registered even when the value fails, so the cli is still parsed and every other problem can be reported
+func parseMeta(fs *flag.FlagSet, meta fieldMeta, lookup SourceFunc, strict bool) error {
 	field := meta.Field
 	if field.Type().Kind() == reflect.Ptr {
 		if field.IsNil() {
@@ -228,81 +292,380 @@ func parseMeta(fs *flag.FlagSet, meta fieldMeta) error {
 		field = field.Elem()
 	}
 
+	err := flagType(field, meta.Tags)
+	if err != nil {
+		err = newFieldError(meta, "", err)
+	}
+
+	v := fieldValue(field, meta.Tags)
+	// a count is also set by a bare -v, so it is left to its flag.Value to parse
+	_, count := v.(*countValue)
+	if strict && basicKind(field.Kind()) && !count && err == nil {
+		val, ok := lookup(tagENV(meta))
+		if ok {
+			if perr := parseValue(val, reflect.New(field.Type()).Elem()); perr != nil {
+				err = newFieldError(meta, val, invalidValue(val, field.Type(), perr))
+				ok = false
+			}
+		}
+		// the value is already known, so it isn't looked up a second time
+		lookup = func(string) (string, bool) { return val, ok }
+	}
+
+	if v == nil {
+		if val, ok := lookup(tagENV(meta)); ok && err == nil {
+			err = newFieldError(meta, val, fmt.Errorf("%w %s", ErrUnsupportedType, field.Type()))
+		}
+		return err
+	}
+
+	if val, ok := lookup(tagENV(meta)); ok {
+		if serr := v.Set(val); serr != nil {
+			if _, basic := v.(*basicValue); basic && field.Kind() != reflect.String {
+				// a bool or number that doesn't parse is left at its zero value, unless strict mode already reported
+				// it. A string only fails on bad base64, which is always reported
+				field.Set(reflect.Zero(field.Type()))
+			} else {
+				err = newFieldError(meta, val, serr)
+			}
+		}
+	}
+	startOver(v)
+	registerFlag(fs, withSecret(withEnum(withBase64(v, meta), meta), meta), field, tagCLI(meta), tagDesc(meta))
+
+	return err
+}
+
+// fieldValue returns the flag.Value that reads and prints field, or nil for a type that can't be set from a string.
+// It is also how values are written back out, so every surface shows a field the same way it is read
+func fieldValue(field reflect.Value, tags reflect.StructTag) flag.Value {
+	if v, ok := flagValue(field, tags); ok {
+		return v
+	}
+
+	// a type with its own parsing, e.g. time.Time or an enum with UnmarshalText, is read the same as from a file
+	if parsesItself(field.Type()) {
+		return &parserValue{field: field}
+	}
+
+	if tags.Get("flagtype") == "count" && flagType(field, tags) == nil {
+		return &countValue{field: field}
+	}
+
+	if basicKind(field.Kind()) {
+		return &basicValue{field: field}
+	}
+
 	switch field.Kind() {
-	case reflect.Bool:
-		v := (*bool)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.BoolVar(v, tagCLI(meta), lookupEnvOrBool(tagENV(meta), false), tagDesc(meta))
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		v := (*int64)(unsafe.Pointer(field.UnsafeAddr()))
-		if meta.Field.Kind() == reflect.Int64 &&
-			field.Type().PkgPath() == "time" &&
-			field.Type().Name() == "Duration" {
-			fs.Int64Var(v, tagCLI(meta), lookupEnvOrDuration(tagENV(meta), int64(0)), tagDesc(meta))
-		} else {
-			fs.Int64Var(v, tagCLI(meta), lookupEnvOrInt64(tagENV(meta), int64(0)), tagDesc(meta))
-		}
-	case reflect.Uint8:
-		v := (*uint)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.UintVar(v, tagCLI(meta), lookupEnvOrUint8(tagENV(meta), uint8(0)), tagDesc(meta))
-	case reflect.Uint16:
-		v := (*uint)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.UintVar(v, tagCLI(meta), lookupEnvOrUint16(tagENV(meta), uint16(0)), tagDesc(meta))
-	case reflect.Uint32:
-		v := (*uint)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.UintVar(v, tagCLI(meta), lookupEnvOrUint32(tagENV(meta), uint32(0)), tagDesc(meta))
-		field.SetUint(uint64(*v))
-	case reflect.Uint64, reflect.Uint:
-		v := (*uint)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.UintVar(v, tagCLI(meta), lookupEnvOrUint64(tagENV(meta), uint64(0)), tagDesc(meta))
-	case reflect.Float32:
-		v := (*float64)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.Float64Var(v, tagCLI(meta), lookupEnvOrFloat32(tagENV(meta), float32(0)), tagDesc(meta))
-	case reflect.Float64:
-		v := (*float64)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.Float64Var(v, tagCLI(meta), lookupEnvOrFloat64(tagENV(meta), float64(0)), tagDesc(meta))
-	case reflect.String:
-		v := (*string)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.StringVar(v, tagCLI(meta), lookupEnvOrString(tagENV(meta), ""), tagDesc(meta))
 	case reflect.Slice:
-		v := (*string)(unsafe.Pointer(field.UnsafeAddr()))
-		fs.StringVar(v, tagCLI(meta), lookupEnvOrString(tagENV(meta), ""), tagDesc(meta))
-		s := reflect.MakeSlice(field.Type(), 0, 0)
-		switch {
-		case field.Type().Kind() == reflect.Uint8:
-			s = reflect.ValueOf([]byte(*v))
-		case len(strings.TrimSpace(*v)) != 0:
-			vs := strings.Split(*v, ",")
-			s = reflect.MakeSlice(field.Type(), len(vs), len(vs))
-			for i, val := range vs {
-				err := parseValue(val, s.Index(i))
-				if err != nil {
-					return err
-				}
-			}
+		return &sliceValue{field: field}
+	case reflect.Map:
+		v := &mapValue{field: field, pairSep: ",", kvSep: "="}
+		if sep, ok := tags.Lookup("mapsep"); ok && sep != "" {
+			v.pairSep = sep
+		}
+		if sep, ok := tags.Lookup("kvsep"); ok && sep != "" {
+			v.kvSep = sep
 		}
-		field.Set(s)
+		return v
+	default:
+		return nil
+	}
+}
+
+// basicKind reports whether fields of kind k are read by a basicValue
+func basicKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
 	}
+}
 
+// invalidValue describes a value that failed to parse as type t, dropping the strconv prefix that repeats the value
+func invalidValue(val string, t reflect.Type, err error) error {
+	var ne *strconv.NumError
+	if errors.As(err, &ne) {
+		err = ne.Err
+	}
+
+	return fmt.Errorf("invalid value %q, expected %s: %w", val, t, err)
+}
+
+// parserValue is the flag.Value for fields whose type implements Decoder, Setter, encoding.TextUnmarshaler or
+// encoding.BinaryUnmarshaler but not flag.Value, setting the field through that method
+type parserValue struct {
+	field reflect.Value
+}
+
+func (p *parserValue) Set(value string) error {
+	return parseValue(value, p.field)
+}
+
+// String uses MarshalText, or MarshalJSON for types read as JSON, when the type has it, so the default shown in
+// usage can be parsed back. Zero values print empty so they are left out of usage
+func (p *parserValue) String() string {
+	if p == nil || !p.field.IsValid() || p.field.IsZero() {
+		return ""
+	}
+
+	switch v := p.field.Addr().Interface().(type) {
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		if err == nil {
+			return string(b)
+		}
+	case json.Marshaler:
+		b, err := v.MarshalJSON()
+		var str string
+		if err == nil && json.Unmarshal(b, &str) == nil {
+			return str
+		}
+		if err == nil {
+			return string(b)
+		}
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	return fmt.Sprint(p.field.Interface())
+}
+
+// mapValue is a flag.Value that reads pairs like key1=val1,key2=val2 into the map field it wraps. The separators
+// default to , between pairs and = between a key and its value, and can be changed with the mapsep: and kvsep: tags
+type mapValue struct {
+	field   reflect.Value
+	pairSep string
+	kvSep   string
+}
+
+func (m *mapValue) Set(value string) error {
+	t := m.field.Type()
+	result := reflect.MakeMap(t)
+	for _, pair := range strings.Split(value, m.pairSep) {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, m.kvSep, 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid map item %q: expected key%svalue", pair, m.kvSep)
+		}
+
+		key := reflect.New(t.Key()).Elem()
+		if err := parseValue(strings.TrimSpace(kv[0]), key); err != nil {
+			return err
+		}
+
+		val := reflect.New(t.Elem()).Elem()
+		if err := parseValue(strings.TrimSpace(kv[1]), val); err != nil {
+			return err
+		}
+
+		result.SetMapIndex(key, val)
+	}
+
+	m.field.Set(result)
 	return nil
 }
 
-func parseValue(v string, field reflect.Value) error {
-	decoder := parseDecoder(field)
-	if decoder != nil {
-		return decoder.Decode(v)
+func (m *mapValue) String() string {
+	if m == nil || !m.field.IsValid() {
+		return ""
 	}
 
-	setter := parseSetter(field)
-	if setter != nil {
-		return setter.Set(v)
+	pairs := make([]string, 0, m.field.Len())
+	for _, key := range m.field.MapKeys() {
+		pairs = append(pairs, fmt.Sprint(key.Interface())+m.kvSep+fmt.Sprint(m.field.MapIndex(key).Interface()))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, m.pairSep)
+}
+
+// sliceValue is a flag.Value that splits a comma separated string into the slice field it wraps. A []byte field is
+// set to the bytes of the string instead, and a slice of structs is read from a JSON array. Items read from numbered
+// keys are joined with itemSep rather than commas. Once it has been set, each later value is appended, so a repeated
+// flag like -HOST a -HOST b,c collects every item, while an empty value clears the slice
+type sliceValue struct {
+	field reflect.Value
+	// given is set once a value has been set, so the next one is appended
+	given bool
+}
+
+func (s *sliceValue) Set(value string) error {
+	if s.field.Type().Elem().Kind() == reflect.Uint8 {
+		b, err := parseBytes(value)
+		if err != nil {
+			return err
+		}
+		s.field.SetBytes(b)
+		return nil
 	}
 
-	if t := textUnmarshaler(field); t != nil {
-		return t.UnmarshalText([]byte(v))
+	slice, err := s.parse(value)
+	if err != nil {
+		return err
 	}
 
-	if b := binaryUnmarshaler(field); b != nil {
-		return b.UnmarshalBinary([]byte(v))
+	if s.given && slice.Len() > 0 {
+		slice = reflect.AppendSlice(s.field, slice)
+	}
+	s.field.Set(slice)
+	s.given = true
+
+	return nil
+}
+
+// startOver makes the next value set on a slice flag replace the slice rather than append to it, so the first time a
+// flag is given on the cli, or a layer ranked above the cli resets it, the value of a lower layer isn't kept
+func startOver(v flag.Value) {
+	if s, ok := v.(*secretValue); ok {
+		v = s.Value
+	}
+	if e, ok := v.(*enumValue); ok {
+		v = e.Value
+	}
+
+	if s, ok := v.(*sliceValue); ok {
+		s.given = false
+	}
+}
+
+// parse reads value into a new slice of the field's type
+func (s *sliceValue) parse(value string) (reflect.Value, error) {
+	if len(strings.TrimSpace(value)) == 0 {
+		return reflect.MakeSlice(s.field.Type(), 0, 0), nil
+	}
+
+	indexed := strings.Contains(value, itemSep)
+	if s.structs() {
+		value = strings.TrimSpace(value)
+		if indexed || strings.HasPrefix(value, "{") {
+			value = "[" + strings.ReplaceAll(value, itemSep, ",") + "]"
+		}
+
+		slice := reflect.New(s.field.Type())
+		if err := json.Unmarshal([]byte(value), slice.Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+		return slice.Elem(), nil
+	}
+
+	sep := ","
+	if indexed {
+		sep = itemSep
+	}
+
+	vs := strings.Split(value, sep)
+	slice := reflect.MakeSlice(s.field.Type(), len(vs), len(vs))
+	for i, val := range vs {
+		err := parseValue(val, slice.Index(i))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return slice, nil
+}
+
+// structs reports whether the slice holds structs that have no way to parse themselves from a string, which are read
+// from a JSON array instead, e.g. [{"host":"a","port":1}]
+func (s *sliceValue) structs() bool {
+	t := s.field.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == ipNetType {
+		return false
+	}
+
+	return !parsesItself(t)
+}
+
+func (s *sliceValue) String() string {
+	if s == nil || !s.field.IsValid() {
+		return ""
+	}
+
+	if s.field.Type().Elem().Kind() == reflect.Uint8 {
+		return string(s.field.Bytes())
+	}
+
+	if s.structs() {
+		b, _ := json.Marshal(s.field.Interface())
+		return string(b)
+	}
+
+	vs := make([]string, s.field.Len())
+	for i := range vs {
+		item := s.field.Index(i)
+		if str, ok := item.Addr().Interface().(fmt.Stringer); ok {
+			vs[i] = str.String()
+			continue
+		}
+		vs[i] = fmt.Sprint(item.Interface())
+	}
+	return strings.Join(vs, ",")
+}
+
+// taggedValue is implemented by field types that read settings from the struct tags of their field, such as a
+// default port, and return the flag.Value used to set them
+type taggedValue interface {
+	flagValue(tags reflect.StructTag) flag.Value
+}
+
+// flagValue returns the flag.Value used to set the field directly, if its type has one
+func flagValue(field reflect.Value, tags reflect.StructTag) (flag.Value, bool) {
+	if t := field.Type(); t == ipType || t == ipNetType || t == urlType {
+		return &netValue{field: field}, true
+	}
+
+	switch v := field.Addr().Interface().(type) {
+	case taggedValue:
+		return v.flagValue(tags), true
+	case flag.Value:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+func parseValue(v string, field reflect.Value) error {
+	// url.URL is checked first as its UnmarshalBinary doesn't validate anything
+	if t := field.Type(); t == urlType || t == reflect.PtrTo(urlType) {
+		return parseURL(v, field)
+	}
+
+	if parsesItself(field.Type()) {
+		if decoder := parseDecoder(field); decoder != nil {
+			return decoder.Decode(v)
+		}
+
+		if setter := parseSetter(field); setter != nil {
+			return setter.Set(v)
+		}
+
+		if t := textUnmarshaler(field); t != nil {
+			return t.UnmarshalText([]byte(v))
+		}
+
+		if b := binaryUnmarshaler(field); b != nil {
+			return b.UnmarshalBinary([]byte(v))
+		}
+
+		if parsesJSON(field.Type()) {
+			if field.Kind() == reflect.Ptr && field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			if j := jsonUnmarshaler(field); j != nil {
+				return j.UnmarshalJSON(jsonText(v, reflect.Indirect(field).Kind()))
+			}
+		}
 	}
 
 	if field.Type().Kind() == reflect.Ptr {
@@ -312,9 +675,13 @@ func parseValue(v string, field reflect.Value) error {
 		field = field.Elem()
 	}
 
+	if field.Type() == ipNetType {
+		return parseCIDR(v, field)
+	}
+
 	switch field.Type().Kind() {
 	case reflect.Bool:
-		val, err := strconv.ParseBool(v)
+		val, err := parseBool(v)
 		if err != nil {
 			return err
 		}
@@ -322,14 +689,12 @@ func parseValue(v string, field reflect.Value) error {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		var val int64
 		var err error
-		if field.Kind() == reflect.Int64 &&
-			field.Type().PkgPath() == "time" &&
-			field.Type().Name() == "Duration" {
+		if field.Type() == durationType {
 			var d time.Duration
-			d, err = time.ParseDuration(v)
+			d, err = parseDuration(v)
 			val = int64(d)
 		} else {
-			val, err = strconv.ParseInt(v, 0, field.Type().Bits())
+			val, err = parseInt(v, field.Type().Bits())
 		}
 		if err != nil {
 			return err
@@ -337,25 +702,46 @@ func parseValue(v string, field reflect.Value) error {
 
 		field.SetInt(val)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		val, err := strconv.ParseUint(v, 0, field.Type().Bits())
+		val, err := parseUint(v, field.Type().Bits())
 		if err != nil {
 			return err
 		}
 		field.SetUint(val)
 	case reflect.Float32, reflect.Float64:
-		val, err := strconv.ParseFloat(v, field.Type().Bits())
+		val, err := parseFloat(v, field.Type().Bits())
 		if err != nil {
 			return err
 		}
 		field.SetFloat(val)
 	case reflect.String:
-		field.SetString(v)
+		val, err := parseString(v)
+		if err != nil {
+			return err
+		}
+		field.SetString(val)
 	}
 
 	return nil
 }
 
+// tagCLI builds the flag name of a field, joining the names of its parent structs with a dash unless a parent is
+// tagged with noprefix:
 func tagCLI(meta fieldMeta) string {
+	if meta.cli != "" {
+		return meta.cli
+	}
+
+	key := tagBaseCLI(meta)
+	for i := len(meta.Parents) - 1; i >= 0; i-- {
+		if !noPrefix(meta.Parents[i]) {
+			key = tagBaseCLI(meta.Parents[i]) + "-" + key
+		}
+	}
+
+	return key
+}
+
+func tagBaseCLI(meta fieldMeta) string {
 	switch {
 	case meta.AltCLI != "":
 		return meta.AltCLI
@@ -368,7 +754,45 @@ func tagCLI(meta fieldMeta) string {
 	}
 }
 
+// tagENV builds the env name of a field, joining the global prefix and the names of its parent structs with an
+// underscore unless a parent is tagged with noprefix:
 func tagENV(meta fieldMeta) string {
+	if meta.env != "" {
+		return meta.env
+	}
+
+	key := tagBaseENV(meta)
+	for i := len(meta.Parents) - 1; i >= 0; i-- {
+		if !noPrefix(meta.Parents[i]) {
+			key = tagBaseENV(meta.Parents[i]) + "_" + key
+		}
+	}
+
+	if meta.Prefix != "" {
+		return meta.Prefix + "_" + key
+	}
+
+	return key
+}
+
+// noPrefix reports whether a nested struct field opted out of prefixing the names of its fields
+func noPrefix(meta fieldMeta) bool {
+	return tagBool(meta.Tags, "noprefix")
+}
+
+// tagBool reports whether the tag named key is set to a true value. Missing tags are not parsed at all, as a failed
+// strconv.ParseBool allocates its error
+func tagBool(tags reflect.StructTag, key string) bool {
+	val := tags.Get(key)
+	if val == "" {
+		return false
+	}
+
+	v, _ := strconv.ParseBool(val)
+	return v
+}
+
+func tagBaseENV(meta fieldMeta) string {
 	switch {
 	case meta.AltENV != "":
 		return meta.AltENV
@@ -390,133 +814,86 @@ func tagDesc(meta fieldMeta) string {
 	}
 }
 
-func lookupEnvOrString(key, defaultVal string) string {
-	if val, ok := os.LookupEnv(key); ok {
-		return val
-	}
-	return defaultVal
-}
+type fieldMeta struct {
+	Name    string
+	AltENV  string
+	AltCLI  string
+	AltJSON string
+	DescCLI string
+	Key     string
+	Field   reflect.Value
+	Tags    reflect.StructTag
+	Prefix  string
+	Parents []fieldMeta
 
-func lookupEnvOrInt64(key string, defaultVal int64) int64 {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseInt(val, 10, 64)
-		if err != nil {
-			return int64(0)
-		}
-		return v
-	}
-	return defaultVal
+	// env and cli cache the names built by tagENV and tagCLI
+	env string
+	cli string
 }
 
-func lookupEnvOrUint8(key string, defaultVal uint8) uint {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseUint(val, 10, 8)
-		if err != nil {
-			return uint(0)
-		}
-		return uint(v)
+func parseInterface(v reflect.Value, fn func(interface{}, *bool)) {
+	if !v.CanInterface() {
+		return
 	}
-	return uint(defaultVal)
-}
 
-func lookupEnvOrUint16(key string, defaultVal uint16) uint {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseUint(val, 10, 16)
-		if err != nil {
-			return uint(0)
-		}
-		return uint(v)
+	var ok bool
+	fn(v.Interface(), &ok)
+	if !ok && v.CanAddr() {
+		fn(v.Addr().Interface(), &ok)
 	}
-	return uint(defaultVal)
 }
 
-func lookupEnvOrUint32(key string, defaultVal uint32) uint {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseUint(val, 10, 32)
-		if err != nil {
-			return uint(0)
-		}
-		return uint(v)
-	}
-	return uint(defaultVal)
+var parserTypes = []reflect.Type{
+	reflect.TypeOf((*Decoder)(nil)).Elem(),
+	reflect.TypeOf((*Setter)(nil)).Elem(),
+	reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem(),
+	reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem(),
 }
 
-func lookupEnvOrUint64(key string, defaultVal uint64) uint {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseUint(val, 10, 64)
-		if err != nil {
-			return uint(0)
-		}
-		return uint(v)
-	}
-	return uint(defaultVal)
-}
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
 
-func lookupEnvOrDuration(key string, defaultVal int64) int64 {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := time.ParseDuration(val)
-		if err != nil {
-			return int64(0)
+// parsesItself reports whether t, or a pointer to it, implements Decoder, Setter, encoding.TextUnmarshaler or
+// encoding.BinaryUnmarshaler, or is a named bool, number or string type that implements json.Unmarshaler. Only the
+// type is checked, so unlike parseDecoder and the rest no values are boxed
+func parsesItself(t reflect.Type) bool {
+	pt := reflect.PtrTo(t)
+	for _, p := range parserTypes {
+		if t.Implements(p) || pt.Implements(p) {
+			return true
 		}
-		return int64(v)
 	}
-	return defaultVal
-}
 
-func lookupEnvOrBool(key string, defaultVal bool) bool {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseBool(val)
-		if err != nil {
-			return false
-		}
-		return v
-	}
-	return defaultVal
+	return parsesJSON(t)
 }
 
-func lookupEnvOrFloat32(key string, defaultVal float32) float64 {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseFloat(val, 32)
-		if err != nil {
-			return float64(0)
-		}
-		return float64(v)
+// parsesJSON reports whether t is a bool, number or string type that implements json.Unmarshaler, such as an enum.
+// Structs, slices and maps with UnmarshalJSON are left to be read field by field and item by item as before
+func parsesJSON(t reflect.Type) bool {
+	e := t
+	if e.Kind() == reflect.Ptr {
+		e = e.Elem()
 	}
-	return float64(defaultVal)
-}
 
-func lookupEnvOrFloat64(key string, defaultVal float64) float64 {
-	if val, ok := os.LookupEnv(key); ok {
-		v, err := strconv.ParseFloat(val, 64)
-		if err != nil {
-			return float64(0)
-		}
-		return v
-	}
-	return defaultVal
+	return basicKind(e.Kind()) && (t.Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(jsonUnmarshalerType))
 }
 
-type fieldMeta struct {
-	Name    string
-	AltENV  string
-	AltCLI  string
-	AltJSON string
-	DescCLI string
-	Key     string
-	Field   reflect.Value
-	Tags    reflect.StructTag
+func jsonUnmarshaler(field reflect.Value) json.Unmarshaler {
+	var j json.Unmarshaler
+	parseInterface(field, func(v interface{}, ok *bool) { j, *ok = v.(json.Unmarshaler) })
+	return j
 }
 
-func parseInterface(v reflect.Value, fn func(interface{}, *bool)) {
-	if !v.CanInterface() {
-		return
+// jsonText turns a value from env, cli or a file into the JSON passed to UnmarshalJSON. Values that are already JSON
+// are passed as they are, except that string types only take JSON strings, and anything else is quoted, so both
+// LEVEL=debug and LEVEL='"debug"' work
+func jsonText(v string, kind reflect.Kind) []byte {
+	t := strings.TrimSpace(v)
+	if json.Valid([]byte(t)) && (kind != reflect.String || strings.HasPrefix(t, `"`)) {
+		return []byte(t)
 	}
 
-	var ok bool
-	fn(v.Interface(), &ok)
-	if !ok && v.CanAddr() {
-		fn(v.Addr().Interface(), &ok)
-	}
+	b, _ := json.Marshal(v)
+	return b
 }
 
 func parseDecoder(field reflect.Value) Decoder {
@@ -554,13 +931,26 @@ func reflectConfig(prefix string, cfg interface{}) ([]fieldMeta, error) {
 		return nil, ErrInvalidConfig
 	}
 
+	metas := appendMetas(make([]fieldMeta, 0, countFields(c.Type())), strings.ToUpper(prefix), c, nil)
+
+	// the names are looked up many times while loading, so they are built once here
+	for i := range metas {
+		metas[i].env = tagENV(metas[i])
+		metas[i].cli = tagCLI(metas[i])
+	}
+
+	return metas, nil
+}
+
+// appendMetas appends the fields of the struct c to metas, flattening nested structs into their fields. Every field of
+// a nested struct shares the same parents slice
+func appendMetas(metas []fieldMeta, prefix string, c reflect.Value, parents []fieldMeta) []fieldMeta {
 	ct := c.Type()
-	metas := make([]fieldMeta, 0, c.NumField())
 	for i := 0; i < c.NumField(); i++ {
 		f := c.Field(i)
 		ft := ct.Field(i)
 
-		if !f.CanSet() {
+		if !f.CanSet() || ignored(ft.Tag) {
 			continue
 		}
 
@@ -581,9 +971,11 @@ func reflectConfig(prefix string, cfg interface{}) ([]fieldMeta, error) {
 			Field:   f,
 			Tags:    ft.Tag,
 			AltCLI:  ft.Tag.Get("envcli"),
-			AltENV:  strings.ToUpper(ft.Tag.Get("envconfig")),
+			AltENV:  envName(ft.Tag),
 			AltJSON: ft.Tag.Get("json"),
 			DescCLI: ft.Tag.Get("clidesc"),
+			Prefix:  prefix,
+			Parents: parents,
 		}
 
 		meta.Key = meta.Name
@@ -592,30 +984,78 @@ func reflectConfig(prefix string, cfg interface{}) ([]fieldMeta, error) {
 			meta.Key = meta.AltENV
 		}
 		meta.Key = strings.ToUpper(meta.Key)
+
+		if f.Kind() == reflect.Struct && f.Type() != ipNetType && !parsesItself(f.Type()) {
+			children := parents
+			if !ft.Anonymous {
+				// the full slice expression makes sure siblings never append into each other's parents
+				children = append(parents[:len(parents):len(parents)], meta)
+			}
+			metas = appendMetas(metas, prefix, f, children)
+			continue
+		}
+
 		metas = append(metas, meta)
+	}
 
-		if f.Kind() == reflect.Struct {
-			if parseDecoder(f) == nil &&
-				parseSetter(f) == nil &&
-				textUnmarshaler(f) == nil &&
-				binaryUnmarshaler(f) == nil {
-				pre := ""
-				if !ft.Anonymous {
-					pre = meta.Key
-				}
+	return metas
+}
 
-				embeddedPtr := f.Addr().Interface()
-				embeddedMetas, err := reflectConfig(pre, embeddedPtr)
-				if err != nil {
-					return nil, err
-				}
-				metas = append(metas[:len(metas)-1], embeddedMetas...)
-				continue
-			}
+// envName returns the first name in the envconfig: tag of a field, upper cased. Any names after it are aliases read
+// by envAliases
+func envName(tags reflect.StructTag) string {
+	name, _, _ := strings.Cut(tags.Get("envconfig"), ",")
+	return strings.ToUpper(strings.TrimSpace(name))
+}
+
+// envAliases returns a meta for each name after the first in the envconfig: tag of meta, e.g.
+// `envconfig:"DB_HOST,DATABASE_HOST"`, which are looked up in order when the first isn't set. The prefix and parent
+// structs are kept, so each alias is built the same way as the field's own env name
+func envAliases(meta fieldMeta) []fieldMeta {
+	names := strings.Split(meta.Tags.Get("envconfig"), ",")
+	if len(names) < 2 {
+		return nil
+	}
+
+	aliases := make([]fieldMeta, 0, len(names)-1)
+	for _, name := range names[1:] {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
 		}
+
+		alias := meta
+		alias.AltENV = strings.ToUpper(name)
+		alias.env, alias.cli = "", ""
+		aliases = append(aliases, alias)
 	}
 
-	return metas, nil
+	return aliases
+}
+
+// ignored reports whether a field is tagged envconfig:"-", envcli:"-" or json:"-", which keeps it out of every flag,
+// env and file, e.g. for a field only set at runtime
+func ignored(tags reflect.StructTag) bool {
+	for _, key := range []string{"envconfig", "envcli", "json"} {
+		if tags.Get(key) == "-" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// countFields counts the fields of t, including the fields of nested structs, to size the slice of metas up front.
+// Pointers to structs are not followed, so the count can be low but never loops on recursive types
+func countFields(t reflect.Type) int {
+	n := 0
+	for i := 0; i < t.NumField(); i++ {
+		if ft := t.Field(i).Type; ft.Kind() == reflect.Struct {
+			n += countFields(ft)
+		}
+		n++
+	}
+
+	return n
 }
 
 func snakify(s string) string {
@@ -646,18 +1086,36 @@ func jsonify(s string) string {
 	return string(formatted)
 }
 
+// tags builds the struct tag of a field from its options, quoting values the same way reflect.StructTag.Get reads them
+// back, so a struct built by BuildConfig loads and describes the same as one written by hand
 func tags(o ConfigurationOption) reflect.StructTag {
 	tag := ""
 	if o.jsonName != "" {
-		tag += ` json:"` + o.jsonName + `" `
+		tag += ` json:` + strconv.Quote(o.jsonName)
 	}
 
 	if o.envName != "" {
-		tag += ` envconfig:"` + o.envName + `" `
+		tag += ` envconfig:` + strconv.Quote(o.envName)
 	}
 
 	if o.useCLI {
-		tag += ` envcli:"` + o.cliName + `" clidesc:"` + o.usage + `"`
+		tag += ` envcli:` + strconv.Quote(o.cliName) + ` clidesc:` + strconv.Quote(o.usage)
+	}
+
+	if o.defaultTag != "" {
+		tag += ` default:` + strconv.Quote(o.defaultTag)
+	}
+
+	if o.required {
+		tag += ` required:"true"`
+	}
+
+	if o.secret {
+		tag += ` secret:"true"`
+	}
+
+	if o.validate != "" {
+		tag += ` validate:` + strconv.Quote(o.validate)
 	}
 
 	return reflect.StructTag(strings.TrimSpace(tag))
@@ -0,0 +1,237 @@
+package ruadan
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrValidation is wrapped by the FieldError of each field that breaks a rule of its validate: tag
+var ErrValidation = errors.New("validation failed")
+
+// ValidationError is returned when fields break the rules set on them with the validate: tag, e.g.
+// `validate:"min=1,max=65535"`. Rules are checked once every layer is merged and every broken one is listed
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Error lists the broken rules
+func (e *ValidationError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Error()
+	}
+
+	return "validation failed: " + strings.Join(fields, ", ")
+}
+
+// Is reports whether any of the broken rules matches target, so errors.Is(err, ErrValidation) holds
+func (e *ValidationError) Is(target error) bool {
+	return fieldsIs(e.Fields, target)
+}
+
+// As finds the first of the broken rules that matches target, such as a *FieldError
+func (e *ValidationError) As(target interface{}) bool {
+	return fieldsAs(e.Fields, target)
+}
+
+// validationErr wraps ErrValidation with a message saying what the rule is
+type validationErr string
+
+func (e validationErr) Error() string {
+	return string(e)
+}
+
+func (e validationErr) Unwrap() error {
+	return ErrValidation
+}
+
+// WithValidator runs fn on the config struct once every layer is merged, in place of the rules of validate: tags, so a
+// validation library that reads the same tag can be used instead. A *FieldError returned by fn, on its own or in a
+// *ValidationError, can set Field to the path of the field, e.g. Server.Port, and leave Key, Flag and Value empty for
+// the load to fill in the same way as for its own errors, hiding the value of secrets
+func WithValidator(fn func(cfg interface{}) error) LoadOptions {
+	return func(o *LoadOption) { o.validator = fn }
+}
+
+// runValidator calls the validator set by WithValidator and fills in the FieldErrors it returns
+func runValidator(cfg interface{}, metas []fieldMeta, fn func(cfg interface{}) error) error {
+	err := fn(cfg)
+	switch e := err.(type) {
+	case *FieldError:
+		fillFieldError(e, metas)
+	case *ValidationError:
+		for _, f := range e.Fields {
+			fillFieldError(f, metas)
+		}
+	}
+
+	return err
+}
+
+// fillFieldError finds the field at the path held in e.Field and fills in the details a validator can't know. Names
+// of embedded structs in the path are skipped over, so the longest field path the path ends with is used
+func fillFieldError(e *FieldError, metas []fieldMeta) {
+	if e.Key != "" {
+		return
+	}
+
+	var found *fieldMeta
+	for i, meta := range metas {
+		path := fieldPath(meta)
+		if e.Field != path && !strings.HasSuffix(e.Field, "."+path) {
+			continue
+		}
+		if found == nil || len(path) > len(fieldPath(*found)) {
+			found = &metas[i]
+		}
+	}
+
+	if found == nil {
+		return
+	}
+
+	e.Field, e.Key, e.Flag = found.Name, tagENV(*found), tagCLI(*found)
+	if e.Value == "" {
+		e.Value = formatMeta(*found)
+	} else {
+		e.Value = redact(*found, e.Value)
+	}
+}
+
+// checkRules returns a ValidationError listing every field that breaks a rule of its validate: tag. The rules are:
+//
+//   - min=N and max=N bound a number, parsed the same way as the field so durations and byte sizes can be written
+//     as 1s or 64MiB, or the length of a string, slice or map
+//   - len=N sets the exact length of a string, slice or map
+//   - oneof=a b c lists the values allowed, separated by spaces and compared the same way they are written to env
+//   - url needs a string to be a URL with a scheme and a host
+//   - omitempty skips the other rules while the field is at its zero value
+//
+// A rule that is unknown or doesn't fit the type of the field is returned on its own, as it is a mistake in the struct
+func checkRules(metas []fieldMeta) error {
+	broken := []*FieldError{}
+	for _, meta := range metas {
+		tag := meta.Tags.Get("validate")
+		if tag == "" {
+			continue
+		}
+
+		rules := strings.Split(tag, ",")
+		if isZero(meta.Field) && containsRule(rules, "omitempty") {
+			continue
+		}
+
+		for _, rule := range rules {
+			name, arg := rule, ""
+			if i := strings.Index(rule, "="); i >= 0 {
+				name, arg = rule[:i], rule[i+1:]
+			}
+
+			msg, err := checkRule(meta, strings.TrimSpace(name), strings.TrimSpace(arg))
+			if err != nil {
+				return newFieldError(meta, "", fmt.Errorf("validate %s: %v", rule, err))
+			}
+
+			if msg != "" {
+				broken = append(broken, newFieldError(meta, formatMeta(meta), validationErr(msg)))
+			}
+		}
+	}
+
+	if len(broken) > 0 {
+		return &ValidationError{Fields: broken}
+	}
+
+	return nil
+}
+
+func containsRule(rules []string, name string) bool {
+	for _, rule := range rules {
+		if strings.TrimSpace(rule) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkRule returns a message saying how the field breaks the rule, or an empty one when it doesn't
+func checkRule(meta fieldMeta, name, arg string) (string, error) {
+	field := meta.Field
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field = reflect.Zero(field.Type().Elem())
+		} else {
+			field = field.Elem()
+		}
+	}
+
+	switch name {
+	case "omitempty", "":
+		return "", nil
+	case "min":
+		return checkBound(meta, field, arg, "at least", func(cmp int) bool { return cmp >= 0 })
+	case "max":
+		return checkBound(meta, field, arg, "at most", func(cmp int) bool { return cmp <= 0 })
+	case "len":
+		return checkBound(meta, field, arg, "exactly", func(cmp int) bool { return cmp == 0 })
+	case "oneof":
+		options := strings.Fields(arg)
+		got := valueString(field, meta.Tags)
+		for _, o := range options {
+			if o == got {
+				return "", nil
+			}
+		}
+		return "must be one of " + strings.Join(options, ", ") + ", got " + quoteEmpty(formatMeta(meta)), nil
+	case "url":
+		if field.Kind() != reflect.String {
+			return "", errors.New("only applies to strings")
+		}
+		if u, err := url.Parse(field.String()); err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a URL with a scheme and a host, got " + quoteEmpty(formatMeta(meta)), nil
+		}
+		return "", nil
+	default:
+		return "", errors.New("unknown rule")
+	}
+}
+
+// checkBound compares a number with arg, or the length of a string, slice or map, and words the message for a failed
+// comparison with desc
+func checkBound(meta fieldMeta, field reflect.Value, arg, desc string, ok func(cmp int) bool) (string, error) {
+	switch field.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid length %q", arg)
+		}
+		if ok(sign(field.Len() < n, field.Len() > n)) {
+			return "", nil
+		}
+		return fmt.Sprintf("length must be %s %d, got %d", desc, n, field.Len()), nil
+	}
+
+	if !isNumber(field) {
+		return "", fmt.Errorf("only applies to numbers, strings, slices and maps, not %s", field.Type())
+	}
+
+	bound := reflect.New(field.Type()).Elem()
+	if err := parseValue(arg, bound); err != nil {
+		return "", fmt.Errorf("invalid bound %q: %v", arg, err)
+	}
+
+	cmp, err := compareFields(field, bound)
+	if err != nil {
+		return "", err
+	}
+	if ok(cmp) {
+		return "", nil
+	}
+
+	return "must be " + desc + " " + arg + ", got " + formatMeta(meta), nil
+}
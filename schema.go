@@ -0,0 +1,127 @@
+package ruadan
+
+import (
+	"sort"
+)
+
+// Schema describes the fields of a config struct as one version of a binary reads them. Schemas are meant to be saved
+// as JSON with a release, so the schema of the version being rolled out can be compared with the one it replaces
+type Schema struct {
+	// Version is the main.version of the binary the schema was taken from, if it was built with one
+	Version string        `json:"version,omitempty"`
+	Fields  []SchemaField `json:"fields"`
+}
+
+// SchemaField is a field of a Schema
+type SchemaField struct {
+	Field    string `json:"field"`
+	Type     string `json:"type"`
+	Env      string `json:"env"`
+	Default  string `json:"default,omitempty"`
+	Required bool   `json:"required,omitempty"`
+}
+
+// needsValue reports whether the field has to be set by the cli, env or a file for a load to succeed
+func (f SchemaField) needsValue() bool {
+	return f.Required && f.Default == ""
+}
+
+// SchemaChange is a difference between two schemas. Breaking changes are ones where the same config can't be loaded
+// by both versions, so old and new instances can't run side by side during a rolling deploy
+type SchemaChange struct {
+	Field    string
+	Breaking bool
+	Message  string
+}
+
+// String formats the SchemaChange as a single line
+func (c SchemaChange) String() string {
+	if c.Breaking {
+		return "breaking: " + c.Field + ": " + c.Message
+	}
+
+	return c.Field + ": " + c.Message
+}
+
+// SchemaOf returns the Schema of cfg, which must be a struct pointer, from the same details as Describe. WithPrefix is
+// honored and other options are ignored
+func SchemaOf(cfg interface{}, options ...LoadOptions) (Schema, error) {
+	infos, err := Describe(cfg, options...)
+	if err != nil {
+		return Schema{}, err
+	}
+
+	s := Schema{Fields: make([]SchemaField, len(infos))}
+	s.Version, _ = lookupBuildInfo("main.version")
+	for i, info := range infos {
+		s.Fields[i] = SchemaField{
+			Field:    info.Field,
+			Type:     info.Type,
+			Env:      info.Env,
+			Default:  info.Default,
+			Required: info.Required,
+		}
+	}
+
+	return s, nil
+}
+
+// Compare returns the changes from s to next, sorted by field. A field that is new and required without a default,
+// becomes required, changes type or changes env is breaking. A field that is removed, new but optional, or has a new
+// default is not, as the config of the old version still loads, but the change is still reported
+func (s Schema) Compare(next Schema) []SchemaChange {
+	current := make(map[string]SchemaField, len(s.Fields))
+	for _, f := range s.Fields {
+		current[f.Field] = f
+	}
+
+	changes := []SchemaChange{}
+	change := func(field string, breaking bool, msg string) {
+		changes = append(changes, SchemaChange{Field: field, Breaking: breaking, Message: msg})
+	}
+
+	seen := make(map[string]bool, len(next.Fields))
+	for _, f := range next.Fields {
+		seen[f.Field] = true
+		was, ok := current[f.Field]
+		switch {
+		case !ok && f.needsValue():
+			change(f.Field, true, "new required field, set "+f.Env+" first")
+			continue
+		case !ok:
+			change(f.Field, false, "new field")
+			continue
+		}
+
+		if was.Type != f.Type {
+			change(f.Field, true, "type changes from "+was.Type+" to "+f.Type)
+		}
+		if was.Env != f.Env {
+			change(f.Field, true, "env changes from "+was.Env+" to "+f.Env)
+		}
+		if !was.needsValue() && f.needsValue() {
+			change(f.Field, true, "becomes required")
+		}
+		if was.Default != f.Default {
+			change(f.Field, false, "default changes from "+quoteEmpty(was.Default)+" to "+quoteEmpty(f.Default))
+		}
+	}
+
+	for _, f := range s.Fields {
+		if !seen[f.Field] {
+			change(f.Field, false, "removed")
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// quoteEmpty shows an empty default as "" so it isn't mistaken for a missing word
+func quoteEmpty(s string) string {
+	if s == "" {
+		return `""`
+	}
+
+	return s
+}
@@ -0,0 +1,75 @@
+package ruadan
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+type redactConfig struct {
+	Password string `envconfig:"DB_PASSWORD" secret:"true" default:"hunter2"`
+	Token    string `envconfig:"TOKEN" secret:"true"`
+	PIN      int    `envconfig:"PIN" secret:"true" default:"1234"`
+	Host     string `envconfig:"DB_HOST" default:"localhost"`
+}
+
+func TestSecretPrintDefaults(t *testing.T) {
+	l := NewLoader(
+		WithArgs([]string{"-PIN", "4321"}),
+		WithSources(MapSource(nil)),
+		WithErrorHandling(flag.ContinueOnError),
+		WithOutput(ioutil.Discard),
+	)
+	cfg := redactConfig{}
+	if err := l.Load(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "hunter2" || cfg.PIN != 4321 {
+		t.Fatalf("secrets should still load, got %+v", cfg)
+	}
+
+	var out bytes.Buffer
+	fs := l.FlagSet()
+	fs.SetOutput(&out)
+	fs.PrintDefaults()
+
+	usage := out.String()
+	for _, leaked := range []string{"hunter2", "1234", "4321"} {
+		if strings.Contains(usage, leaked) {
+			t.Errorf("PrintDefaults shows secret %q:\n%s", leaked, usage)
+		}
+	}
+	if !strings.Contains(usage, Redacted) {
+		t.Errorf("PrintDefaults should show %s for a secret with a default:\n%s", Redacted, usage)
+	}
+	if !strings.Contains(usage, `(default "localhost")`) {
+		t.Errorf("PrintDefaults should still show other defaults:\n%s", usage)
+	}
+
+	for _, name := range []string{"DB_PASSWORD", "PIN", "TOKEN"} {
+		if f := fs.Lookup(name); f == nil || strings.Contains(f.DefValue, "hunter2") || f.Value.String() == "4321" {
+			t.Errorf("flag %s isn't redacted", name)
+		}
+	}
+	if f := fs.Lookup("TOKEN"); f == nil || f.DefValue != "" {
+		t.Errorf("an unset secret should have no default, got %+v", f)
+	}
+
+	if got := l.Lock().Values["PIN"]; got != "4321" {
+		t.Errorf("lock PIN = %q, want the real value 4321", got)
+	}
+}
+
+func TestSecretPrecedence(t *testing.T) {
+	cfg := redactConfig{}
+	err := testLoad(&cfg, nil, []string{"-DB_PASSWORD", "cli"}, nil, WithPrecedence(Env, Default))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "hunter2" {
+		t.Errorf("Password = %q, want the real default hunter2 when cli isn't read", cfg.Password)
+	}
+}
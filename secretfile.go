@@ -0,0 +1,108 @@
+package ruadan
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+)
+
+// FilePrefix marks a config value that is read from a file, followed by the path, e.g. file:///run/secrets/db_password
+const FilePrefix = "file://"
+
+// FileEnvSuffix is added to the env name of a field for the env that names a file holding its value, e.g.
+// DB_PASSWORD_FILE=/run/secrets/db_password, the convention of Docker and Kubernetes secrets
+const FileEnvSuffix = "_FILE"
+
+// SecretFiles returns a Resolver that replaces values starting with FilePrefix with the contents of the file they
+// name, read from fsys or the operating system when fsys is nil, leaving every other value alone. A single trailing
+// newline is dropped, as most tools that write secrets add one. Add it with WithResolver, or use WithSecretFiles
+func SecretFiles(fsys fs.FS) Resolver {
+	return ResolverFunc(func(ctx context.Context, value string) (string, bool, error) {
+		if !strings.HasPrefix(value, FilePrefix) {
+			return "", false, nil
+		}
+
+		val, err := readSecretFile(fsys, strings.TrimPrefix(value, FilePrefix))
+		if err != nil {
+			return "", false, err
+		}
+
+		return val, true, nil
+	})
+}
+
+// WithSecretFiles reads fields from files, for secrets mounted as files by Docker or Kubernetes. When the env of a
+// field isn't set its env with FileEnvSuffix added, e.g. DB_PASSWORD_FILE, names the file holding the value, which is
+// read along with the env so fields of any type, WithExpand and WithTemplates all see the contents. A string value of
+// file:///run/secrets/db_password, from any layer, is also replaced with the contents of the file, after WithExpand
+// and WithTemplates and before any other Resolver. Files are read with WithFS when it is set
+func WithSecretFiles() LoadOptions {
+	return func(o *LoadOption) { o.secretFiles = true }
+}
+
+// secretFile is the value read from the file named by the FileEnvSuffix env of a field
+type secretFile struct {
+	src  Source
+	name string
+	val  string
+	err  error
+}
+
+// lookupSecretFile looks up the env naming the file that holds the value of key and reads it, returning the source
+// and name of the env along with the contents. Each file is read once a load, and one that can't be read is kept in
+// opt.secrets for the load to report
+func (opt *LoadOption) lookupSecretFile(ctx context.Context, key string) (Source, string, string, bool) {
+	if f, ok := opt.secrets[key]; ok {
+		return f.src, f.name, f.val, f.err == nil
+	}
+
+	src, path, ok := opt.sources.lookup(ctx, key+FileEnvSuffix)
+	if !ok || path == "" {
+		return nil, "", "", false
+	}
+
+	val, err := readSecretFile(opt.fsys, path)
+	if err != nil {
+		err = fmt.Errorf("%s%s=%s: %w", key, FileEnvSuffix, path, err)
+	}
+	if opt.secrets != nil {
+		opt.secrets[key] = secretFile{src: src, name: key + FileEnvSuffix, val: val, err: err}
+	}
+
+	return src, key + FileEnvSuffix, val, err == nil
+}
+
+// secretFileErrors returns a FieldError for each field whose FileEnvSuffix env names a file that couldn't be read
+func (opt *LoadOption) secretFileErrors(metas []fieldMeta) map[string]error {
+	errs := map[string]error{}
+	for _, meta := range metas {
+		if f, ok := opt.secrets[tagENV(meta)]; ok && f.err != nil {
+			errs[tagENV(meta)] = newFieldError(meta, "", f.err)
+		}
+	}
+
+	return errs
+}
+
+// readSecretFile reads the file at path from fsys, or the operating system when fsys is nil, dropping a single
+// trailing newline
+func readSecretFile(fsys fs.FS, path string) (string, error) {
+	f, err := openFile(fsys, path)
+	if err != nil {
+		return "", fmt.Errorf("secret file: %w", err)
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", fmt.Errorf("secret file: %w", err)
+	}
+
+	s := string(b)
+	if strings.HasSuffix(s, "\r\n") {
+		return strings.TrimSuffix(s, "\r\n"), nil
+	}
+	return strings.TrimSuffix(s, "\n"), nil
+}
@@ -0,0 +1,115 @@
+package ruadan
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type secretConfig struct {
+	Password string `envconfig:"DB_PASSWORD"`
+	Port     int    `envconfig:"DB_PORT"`
+	Key      []byte `envconfig:"TLS_KEY"`
+	DSN      string `envconfig:"DSN"`
+}
+
+func TestSecretFiles(t *testing.T) {
+	files := map[string]string{
+		"/run/secrets/password": "hunter2\n",
+		"/run/secrets/port":     "5432\r\n",
+		"/run/secrets/key":      "key\n\n",
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		options []LoadOptions
+		want    secretConfig
+	}{
+		{
+			name: "string",
+			env:  map[string]string{"DB_PASSWORD_FILE": "/run/secrets/password"},
+			want: secretConfig{Password: "hunter2"},
+		},
+		{
+			name: "int",
+			env:  map[string]string{"DB_PORT_FILE": "/run/secrets/port"},
+			want: secretConfig{Port: 5432},
+		},
+		{
+			name: "bytes keep all but one newline",
+			env:  map[string]string{"TLS_KEY_FILE": "/run/secrets/key"},
+			want: secretConfig{Key: []byte("key\n")},
+		},
+		{
+			name: "env wins",
+			env:  map[string]string{"DB_PASSWORD": "env", "DB_PASSWORD_FILE": "/run/secrets/password"},
+			want: secretConfig{Password: "env"},
+		},
+		{
+			name: "file value",
+			env:  map[string]string{"DB_PASSWORD": "file:///run/secrets/password"},
+			want: secretConfig{Password: "hunter2"},
+		},
+		{
+			name: "expanded",
+			env: map[string]string{
+				"DB_PASSWORD_FILE": "/run/secrets/password",
+				"DSN":              "pg://app:${DB_PASSWORD}@db",
+			},
+			options: []LoadOptions{WithExpand()},
+			want:    secretConfig{Password: "hunter2", DSN: "pg://app:hunter2@db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg secretConfig
+			options := append([]LoadOptions{WithSecretFiles(), WithStrict()}, tt.options...)
+			if err := testLoad(&cfg, tt.env, nil, files, options...); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("loaded %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretFilesOff(t *testing.T) {
+	var cfg secretConfig
+	env := map[string]string{"DB_PASSWORD_FILE": "/run/secrets/password", "DSN": "file:///run/secrets/password"}
+	if err := testLoad(&cfg, env, nil, map[string]string{"/run/secrets/password": "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Password != "" || cfg.DSN != "file:///run/secrets/password" {
+		t.Errorf("read secret files without WithSecretFiles: %+v", cfg)
+	}
+}
+
+func TestSecretFilesMissing(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "file env", env: map[string]string{"DB_PORT_FILE": "/run/secrets/nope"}},
+		{name: "file value", env: map[string]string{"DB_PASSWORD": "file:///run/secrets/nope"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg secretConfig
+			err := testLoad(&cfg, tt.env, nil, nil, WithSecretFiles())
+			if !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), "secret file") {
+				t.Errorf("expected a secret file error wrapping fs.ErrNotExist, got %v", err)
+			}
+
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				t.Errorf("expected a FieldError, got %T", err)
+			}
+		})
+	}
+}
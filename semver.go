@@ -0,0 +1,317 @@
+package ruadan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a semantic version (https://semver.org) field type. It can be set from env or cli with or without a
+// leading v, e.g. 1.2.3, v1.2.3-rc.1+build.5
+type SemVer struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Build      string
+}
+
+// ParseSemVer parses a full semantic version
+func ParseSemVer(s string) (SemVer, error) {
+	v, parts, err := parseVersion(s)
+	if err != nil {
+		return SemVer{}, err
+	}
+
+	if parts != 3 {
+		return SemVer{}, fmt.Errorf("invalid semantic version %q: expected major.minor.patch", s)
+	}
+
+	return v, nil
+}
+
+// Set parses the value as a semantic version, used when reading from env or cli
+func (v *SemVer) Set(value string) error {
+	parsed, err := ParseSemVer(value)
+	if err != nil {
+		return err
+	}
+
+	*v = parsed
+	return nil
+}
+
+// String returns the version without a leading v, or an empty string for the zero value
+func (v *SemVer) String() string {
+	if v == nil || *v == (SemVer{}) {
+		return ""
+	}
+
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than other. Build metadata is ignored and
+// prerelease versions sort before their release, as described by the semver spec
+func (v SemVer) Compare(other SemVer) int {
+	switch {
+	case v.Major != other.Major:
+		return compareUint(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return compareUint(v.Minor, other.Minor)
+	case v.Patch != other.Patch:
+		return compareUint(v.Patch, other.Patch)
+	}
+
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// VersionConstraint is a field type holding a set of version constraints, e.g. ">=1.2.0, <2.0.0", "^1.4" or
+// "~1.2.3 || >=3.0.0". Constraints separated by a comma or space must all match, groups separated by || are
+// alternatives. Supported operators are =, !=, >, >=, <, <=, ^ (same major) and ~ (same minor)
+type VersionConstraint struct {
+	raw    string
+	groups [][]versionCheck
+}
+
+type versionCheck struct {
+	op      string
+	version SemVer
+}
+
+// ParseVersionConstraint parses a constraint expression
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	c := VersionConstraint{raw: strings.TrimSpace(s)}
+	for _, group := range strings.Split(s, "||") {
+		checks := []versionCheck{}
+		pending := ""
+		for _, field := range strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if strings.Trim(field, "=<>!^~") == "" {
+				pending += field
+				continue
+			}
+			field, pending = pending+field, ""
+			op := field[:len(field)-len(strings.TrimLeft(field, "=<>!^~"))]
+			if op == "" {
+				op = "="
+			}
+
+			v, parts, err := parseVersion(strings.TrimLeft(field, "=<>!^~"))
+			if err != nil {
+				return VersionConstraint{}, fmt.Errorf("invalid version constraint %q: %v", s, err)
+			}
+
+			switch op {
+			case "=", "!=", ">", ">=", "<", "<=":
+				checks = append(checks, versionCheck{op: op, version: v})
+			case "^":
+				upper := SemVer{Major: v.Major + 1}
+				if v.Major == 0 && parts > 1 {
+					upper = SemVer{Minor: v.Minor + 1}
+				}
+				checks = append(checks, versionCheck{op: ">=", version: v}, versionCheck{op: "<", version: upper})
+			case "~":
+				upper := SemVer{Major: v.Major, Minor: v.Minor + 1}
+				if parts == 1 {
+					upper = SemVer{Major: v.Major + 1}
+				}
+				checks = append(checks, versionCheck{op: ">=", version: v}, versionCheck{op: "<", version: upper})
+			default:
+				return VersionConstraint{}, fmt.Errorf("invalid version constraint %q: unknown operator %q", s, op)
+			}
+		}
+
+		if len(checks) == 0 {
+			return VersionConstraint{}, fmt.Errorf("invalid version constraint %q: empty constraint", s)
+		}
+		c.groups = append(c.groups, checks)
+	}
+
+	return c, nil
+}
+
+// Set parses the value as a constraint expression, used when reading from env or cli
+func (c *VersionConstraint) Set(value string) error {
+	parsed, err := ParseVersionConstraint(value)
+	if err != nil {
+		return err
+	}
+
+	*c = parsed
+	return nil
+}
+
+// String returns the constraint expression as it was set
+func (c *VersionConstraint) String() string {
+	if c == nil {
+		return ""
+	}
+
+	return c.raw
+}
+
+// Check reports whether the version satisfies the constraint. An empty constraint allows every version
+func (c VersionConstraint) Check(v SemVer) bool {
+	if len(c.groups) == 0 {
+		return true
+	}
+
+	for _, group := range c.groups {
+		ok := true
+		for _, check := range group {
+			if !check.matches(v) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c versionCheck) matches(v SemVer) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	default:
+		return cmp <= 0
+	}
+}
+
+// parseVersion parses a version that may be missing its minor and patch numbers, returning how many numbers it had
+func parseVersion(s string) (SemVer, int, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	var v SemVer
+
+	if i := strings.Index(s, "+"); i >= 0 {
+		v.Build = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.Build, false) {
+			return SemVer{}, 0, fmt.Errorf("invalid build metadata %q", v.Build)
+		}
+	}
+
+	if i := strings.Index(s, "-"); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if !validIdentifiers(v.Prerelease, true) {
+			return SemVer{}, 0, fmt.Errorf("invalid prerelease %q", v.Prerelease)
+		}
+	}
+
+	nums := strings.Split(s, ".")
+	if len(nums) > 3 {
+		return SemVer{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+
+	for i, n := range nums {
+		if n == "" || (len(n) > 1 && n[0] == '0') {
+			return SemVer{}, 0, fmt.Errorf("invalid version number %q", n)
+		}
+
+		u, err := strconv.ParseUint(n, 10, 64)
+		if err != nil {
+			return SemVer{}, 0, fmt.Errorf("invalid version number %q", n)
+		}
+
+		switch i {
+		case 0:
+			v.Major = u
+		case 1:
+			v.Minor = u
+		default:
+			v.Patch = u
+		}
+	}
+
+	return v, len(nums), nil
+}
+
+func validIdentifiers(s string, noLeadingZero bool) bool {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+
+		numeric := true
+		for _, r := range id {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+
+		if noLeadingZero && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			return compareUint(an, bn)
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		case as[i] < bs[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	return compareUint(uint64(len(as)), uint64(len(bs)))
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,108 @@
+package ruadan
+
+import (
+	"flag"
+	"fmt"
+	"unicode/utf8"
+)
+
+// shortValue is the flag.Value of a short: alias, which sets the same value as the flag it stands for
+type shortValue struct {
+	flag.Value
+	// name is the flag the alias stands for
+	name string
+}
+
+func (s *shortValue) String() string {
+	// a zero value prints empty, so flag.PrintDefaults leaves it out of the default of the alias
+	if s == nil || s.Value == nil || zeroDefault(s.Value.String()) {
+		return ""
+	}
+
+	return s.Value.String()
+}
+
+func (s *shortValue) aliasOf() string {
+	return s.name
+}
+
+func (s *shortValue) typeName() string {
+	return valueTypeName(s.Value)
+}
+
+// IsBoolFlag lets the alias of a bool field be set with a bare -v, the same as the flag it stands for
+func (s *shortValue) IsBoolFlag() bool {
+	b, ok := s.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// registerShorts adds a flag for every field with a short: tag, e.g. `short:"p"` so -p 8080 sets -PORT. They are added
+// once every other flag is, so an alias that is more than one letter or is already taken is an error rather than a
+// panic from the flag package
+func registerShorts(fs *flag.FlagSet, metas []fieldMeta) error {
+	errs := []error{}
+	for _, meta := range metas {
+		short := meta.Tags.Get("short")
+		if short == "" {
+			continue
+		}
+
+		long := fs.Lookup(tagCLI(meta))
+		switch {
+		case long == nil:
+			continue
+		case utf8.RuneCountInString(short) != 1:
+			errs = append(errs, newFieldError(meta, "", fmt.Errorf("short: %q must be a single letter", short)))
+		case fs.Lookup(short) != nil:
+			errs = append(errs, newFieldError(meta, "", fmt.Errorf("short: -%s is already a flag", short)))
+		default:
+			fs.Var(&shortValue{Value: long.Value, name: long.Name}, short, long.Usage)
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// aliasValue is the flag.Value of a flag that stands for another, such as a short: alias or a -no- bool flag
+type aliasValue interface {
+	aliasOf() string
+}
+
+// setFlags returns the flags set on the cli keyed by name. A flag set through an alias is returned as the flag it
+// stands for, so a bool set with -no-VERBOSE is the VERBOSE flag
+func setFlags(fs *flag.FlagSet) map[string]*flag.Flag {
+	set := map[string]*flag.Flag{}
+	fs.Visit(func(f *flag.Flag) {
+		if a, ok := f.Value.(aliasValue); ok {
+			if target := fs.Lookup(a.aliasOf()); target != nil {
+				set[target.Name] = target
+			}
+			return
+		}
+		set[f.Name] = f
+	})
+
+	return set
+}
+
+// setFlagNames is setFlags for callers that only need to know whether a flag was set
+func setFlagNames(fs *flag.FlagSet) map[string]bool {
+	names := map[string]bool{}
+	for name := range setFlags(fs) {
+		names[name] = true
+	}
+
+	return names
+}
+
+// shortNames returns the short: alias of every flag that has one, keyed by the name of the flag
+func shortNames(fs *flag.FlagSet) map[string]string {
+	shorts := map[string]string{}
+	fs.VisitAll(func(f *flag.Flag) {
+		if s, ok := f.Value.(*shortValue); ok {
+			shorts[s.name] = f.Name
+		}
+	})
+
+	return shorts
+}
@@ -0,0 +1,175 @@
+package ruadan
+
+import (
+	"context"
+	"flag"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Source is somewhere config values can be read from. Lookup is called with the env name of each field, e.g.
+// TEST_STRING, and reports whether the source has a value for it
+type Source interface {
+	Lookup(key string) (string, bool)
+}
+
+// ContextSource is a Source whose lookups can be cancelled or time limited, for sources that read over the network or
+// from slow storage. LookupContext is used instead of Lookup, with the context given to Loader.Load or
+// GetConfigFlagSetContext, and should give up and report false once ctx is done
+type ContextSource interface {
+	Source
+	LookupContext(ctx context.Context, key string) (string, bool)
+}
+
+// TagSource is a Source that reads fields by the value of one of their struct tags instead of their env name, e.g. a
+// secret path in a vault: tag. LookupTag is called for fields that have the tag named by Tag, every other field is
+// looked up with Lookup as usual
+type TagSource interface {
+	Source
+	Tag() string
+	LookupTag(ctx context.Context, value string) (string, bool)
+}
+
+// SourceFunc adapts a function to a Source
+type SourceFunc func(key string) (string, bool)
+
+// Lookup calls f(key)
+func (f SourceFunc) Lookup(key string) (string, bool) {
+	return f(key)
+}
+
+// EnvSource is the Source that reads environment variables. It is the first source used by GetConfigFlagSet
+type EnvSource struct{}
+
+// Lookup reads the environment variable named by key
+func (EnvSource) Lookup(key string) (string, bool) {
+	return os.LookupEnv(key)
+}
+
+// MapSource is a Source backed by a map of env names to values, useful in tests or to load values fetched elsewhere
+type MapSource map[string]string
+
+// Lookup reads the value stored under key
+func (m MapSource) Lookup(key string) (string, bool) {
+	val, ok := m[key]
+	return val, ok
+}
+
+// WithSource adds a Source that is checked after env and any earlier sources, but before files
+func WithSource(s Source) LoadOptions {
+	return func(o *LoadOption) { o.sources = append(o.sources, s) }
+}
+
+// WithSources replaces all of the sources, including env, with the ones given. They are checked in order and sit
+// above files and below cli
+func WithSources(sources ...Source) LoadOptions {
+	return func(o *LoadOption) { o.sources = append([]Source{}, sources...) }
+}
+
+type sources []Source
+
+func (srcs sources) lookup(ctx context.Context, key string) (Source, string, bool) {
+	return srcs.lookupField(ctx, key, "")
+}
+
+// lookupField looks up a field by its env name, or by its tag for a TagSource when the field has that tag
+func (srcs sources) lookupField(ctx context.Context, key string, tags reflect.StructTag) (Source, string, bool) {
+	stats := sourceStatsFrom(ctx)
+	fuzzy := fuzzyEnvFrom(ctx)
+	lookup := func(i int, s Source) (string, bool) {
+		if val, ok := lookupSource(ctx, s, key, tags); ok || fuzzy == nil {
+			return val, ok
+		}
+		return fuzzy.lookup(i, s, key)
+	}
+
+	for i, s := range srcs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if stats == nil {
+			if val, ok := lookup(i, s); ok {
+				return s, val, true
+			}
+			continue
+		}
+
+		start := time.Now()
+		val, ok := lookup(i, s)
+		stats.record(i, key, ok, time.Since(start))
+		if ok {
+			return s, val, true
+		}
+	}
+
+	return nil, "", false
+}
+
+func lookupSource(ctx context.Context, s Source, key string, tags reflect.StructTag) (string, bool) {
+	if ts, ok := s.(TagSource); ok {
+		if tag, ok := tags.Lookup(ts.Tag()); ok {
+			return ts.LookupTag(ctx, tag)
+		}
+	}
+
+	if cs, ok := s.(ContextSource); ok {
+		return cs.LookupContext(ctx, key)
+	}
+
+	return s.Lookup(key)
+}
+
+// lookupAliases looks up the env names of metas in srcs in order, returning the source and name of the first one set
+func (srcs sources) lookupAliases(ctx context.Context, metas []fieldMeta) (Source, string, string, bool) {
+	for _, meta := range metas {
+		key := tagENV(meta)
+		if src, val, ok := srcs.lookup(ctx, key); ok {
+			return src, key, val, true
+		}
+	}
+
+	return nil, "", "", false
+}
+
+// itemSep joins the items of an indexed list so a sliceValue can split them apart again. Env values can't hold a NUL
+// byte, so items are free to contain commas
+const itemSep = "\x00"
+
+// lookupIndexed reads a slice field from numbered keys, KEY_0, KEY_1 and so on, stopping at the first missing one.
+// Tags are not used, as a TagSource would return the same value for every key
+func (srcs sources) lookupIndexed(ctx context.Context, key string) (string, bool) {
+	var items []string
+	for i := 0; ; i++ {
+		_, val, ok := srcs.lookup(ctx, key+"_"+strconv.Itoa(i))
+		if !ok {
+			break
+		}
+		items = append(items, val)
+	}
+
+	if len(items) == 0 {
+		return "", false
+	}
+
+	return strings.Join(items, itemSep), true
+}
+
+// indexedSlice reports whether a field of type t is a slice that can be read from numbered keys, which excludes
+// []byte and slice types that set themselves, like Endpoints
+func indexedSlice(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+
+	p := reflect.PtrTo(t)
+	return !p.Implements(reflect.TypeOf((*flag.Value)(nil)).Elem()) &&
+		!p.Implements(reflect.TypeOf((*taggedValue)(nil)).Elem())
+}
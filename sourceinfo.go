@@ -0,0 +1,88 @@
+package ruadan
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// CheckedSource is a Source that can tell whether it is reachable, e.g. by pinging the server it reads from. Check is
+// called once at the start of every load, and a source that fails it is still looked up
+type CheckedSource interface {
+	Source
+	Check(ctx context.Context) error
+}
+
+// SourceInfo reports how a Source was used by a load, for startup logs and support bundles
+type SourceInfo struct {
+	// Name is the Name of a source that has a Name method, otherwise its type, e.g. ruadan.EnvSource
+	Name string
+	// Reachable is false when the Check of a CheckedSource failed, with the error in Err
+	Reachable bool
+	Err       error
+	// Keys is how many different keys the source had a value for
+	Keys int
+	// Lookups is how many times the source was asked for a key, and Duration the time those lookups took
+	Lookups  int
+	Duration time.Duration
+}
+
+// Sources returns a SourceInfo for every source used by the last call to Load, in the order they are checked, or nil
+// if nothing has been loaded
+func (l *Loader) Sources() []SourceInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]SourceInfo(nil), l.sources...)
+}
+
+type sourceStatsKey struct{}
+
+// sourceStats collects the SourceInfo of each source during a load. It travels in the context so every lookup is
+// counted without wrapping the sources, which would hide the interfaces they implement
+type sourceStats struct {
+	infos []SourceInfo
+	keys  []map[string]bool
+}
+
+// withSourceStats checks every CheckedSource and returns a context that records the lookups made to srcs
+func withSourceStats(ctx context.Context, srcs sources) (context.Context, *sourceStats) {
+	stats := &sourceStats{infos: make([]SourceInfo, len(srcs)), keys: make([]map[string]bool, len(srcs))}
+	for i, s := range srcs {
+		stats.infos[i] = SourceInfo{Name: sourceName(s), Reachable: true}
+		stats.keys[i] = map[string]bool{}
+		if cs, ok := s.(CheckedSource); ok {
+			if err := cs.Check(ctx); err != nil {
+				stats.infos[i].Reachable = false
+				stats.infos[i].Err = err
+			}
+		}
+	}
+
+	return context.WithValue(ctx, sourceStatsKey{}, stats), stats
+}
+
+func sourceStatsFrom(ctx context.Context) *sourceStats {
+	stats, _ := ctx.Value(sourceStatsKey{}).(*sourceStats)
+	return stats
+}
+
+func (s *sourceStats) record(i int, key string, found bool, d time.Duration) {
+	if i >= len(s.infos) {
+		return
+	}
+
+	s.infos[i].Lookups++
+	s.infos[i].Duration += d
+	if found && !s.keys[i][key] {
+		s.keys[i][key] = true
+		s.infos[i].Keys++
+	}
+}
+
+func sourceName(s Source) string {
+	if n, ok := s.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+
+	return fmt.Sprintf("%T", s)
+}
@@ -0,0 +1,18 @@
+module github.com/bit-cmdr/ruadan/sources/aws
+
+go 1.22
+
+require (
+	github.com/aws/aws-sdk-go-v2 v1.32.7
+	github.com/aws/aws-sdk-go-v2/service/kms v1.37.8
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.34.8
+	github.com/bit-cmdr/ruadan v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/aws/aws-sdk-go-v2/internal/configsources v1.3.26 // indirect
+	github.com/aws/aws-sdk-go-v2/internal/endpoints/v2 v2.6.26 // indirect
+	github.com/aws/smithy-go v1.22.1 // indirect
+)
+
+replace github.com/bit-cmdr/ruadan => ../..
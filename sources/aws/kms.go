@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/bit-cmdr/ruadan"
+)
+
+// KMSAPI is the part of the KMS client used to decrypt, satisfied by *kms.Client
+type KMSAPI interface {
+	Decrypt(ctx context.Context, in *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSKey is a ruadan.KeyProvider that decrypts with AWS KMS, so the key never leaves KMS. Ciphertext is the
+// CiphertextBlob returned by Encrypt
+type KMSKey struct {
+	// KeyID is the key the ciphertext must have been encrypted with, as an ID, ARN or alias. It can be left empty for
+	// symmetric keys, whose ciphertext records the key
+	KeyID string
+
+	client KMSAPI
+}
+
+var _ ruadan.KeyProvider = (*KMSKey)(nil)
+
+// NewKMSKey creates a KMSKey decrypting with client and the key keyID, which may be empty
+func NewKMSKey(client KMSAPI, keyID string) *KMSKey {
+	return &KMSKey{KeyID: keyID, client: client}
+}
+
+// Decrypt sends ciphertext to KMS and returns the plaintext
+func (k *KMSKey) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	in := &kms.DecryptInput{CiphertextBlob: ciphertext}
+	if k.KeyID != "" {
+		in.KeyId = aws.String(k.KeyID)
+	}
+
+	out, err := k.client.Decrypt(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("kms: %v", err)
+	}
+
+	return out.Plaintext, nil
+}
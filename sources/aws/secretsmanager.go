@@ -0,0 +1,120 @@
+// Package aws resolves ruadan config values from AWS Secrets Manager and decrypts them with KMS. It lives in its own
+// module so the core ruadan module stays free of dependencies
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	sm "github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/bit-cmdr/ruadan"
+)
+
+// Scheme is the prefix of the values resolved by SecretsManager
+const Scheme = "secretsmanager://"
+
+// SecretsManagerAPI is the part of the Secrets Manager client used to read secrets, satisfied by *sm.Client
+type SecretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, in *sm.GetSecretValueInput, optFns ...func(*sm.Options)) (
+		*sm.GetSecretValueOutput, error,
+	)
+}
+
+// SecretsManager is a ruadan.Resolver that replaces values like secretsmanager://name with the secret called name, or
+// secretsmanager://name#field with one field of a JSON secret. The name can also be an ARN. Each secret is fetched
+// once and cached until Invalidate is called
+type SecretsManager struct {
+	client SecretsManagerAPI
+
+	mu    sync.Mutex
+	cache map[string]string
+}
+
+var _ ruadan.Resolver = (*SecretsManager)(nil)
+
+// NewSecretsManager creates a SecretsManager reading secrets with client
+func NewSecretsManager(client SecretsManagerAPI) *SecretsManager {
+	return &SecretsManager{client: client}
+}
+
+// Resolve replaces a secretsmanager:// reference with its secret, leaving any other value alone
+func (s *SecretsManager) Resolve(ctx context.Context, value string) (string, bool, error) {
+	if !strings.HasPrefix(value, Scheme) {
+		return "", false, nil
+	}
+
+	name, field := strings.TrimPrefix(value, Scheme), ""
+	if i := strings.LastIndex(name, "#"); i >= 0 {
+		name, field = name[:i], name[i+1:]
+	}
+
+	if name == "" {
+		return "", false, fmt.Errorf("secretsmanager: %q is missing the secret name", value)
+	}
+
+	secret, err := s.secret(ctx, name)
+	if err != nil {
+		return "", false, err
+	}
+
+	if field == "" {
+		return secret, true, nil
+	}
+
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal([]byte(secret), &fields); err != nil {
+		return "", false, fmt.Errorf("secretsmanager: %s is not a JSON object: %v", name, err)
+	}
+
+	val, ok := fields[field]
+	if !ok {
+		return "", false, fmt.Errorf("secretsmanager: %s has no field %s", name, field)
+	}
+
+	if str, ok := val.(string); ok {
+		return str, true, nil
+	}
+
+	b, err := json.Marshal(val)
+	if err != nil {
+		return "", false, fmt.Errorf("secretsmanager: %s#%s: %v", name, field, err)
+	}
+
+	return string(b), true, nil
+}
+
+// Invalidate drops every cached secret, so the next reference to each is fetched again
+func (s *SecretsManager) Invalidate() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
+
+func (s *SecretsManager) secret(ctx context.Context, name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if val, ok := s.cache[name]; ok {
+		return val, nil
+	}
+
+	out, err := s.client.GetSecretValue(ctx, &sm.GetSecretValueInput{SecretId: aws.String(name)})
+	if err != nil {
+		return "", fmt.Errorf("secretsmanager: %s: %v", name, err)
+	}
+
+	val := aws.ToString(out.SecretString)
+	if out.SecretString == nil {
+		val = string(out.SecretBinary)
+	}
+
+	if s.cache == nil {
+		s.cache = map[string]string{}
+	}
+	s.cache[name] = val
+	return val, nil
+}
@@ -0,0 +1,150 @@
+// Package etcd reads ruadan config values from etcd v3. It lives in its own module so the core ruadan module stays
+// free of dependencies
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bit-cmdr/ruadan"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Source is a ruadan.Source that reads values from the etcd keys under a prefix. A field with the env name DB_HOST
+// is read from the key prefix+DB_HOST, e.g. /myapp/DB_HOST. Every key under the prefix is fetched up front, so
+// lookups never go over the network
+type Source struct {
+	client *clientv3.Client
+	prefix string
+
+	mu     sync.RWMutex
+	values map[string]string
+	rev    int64
+}
+
+var _ ruadan.Source = (*Source)(nil)
+
+// New creates a Source for the keys under prefix and fetches their values
+func New(ctx context.Context, client *clientv3.Client, prefix string) (*Source, error) {
+	s := &Source{client: client, prefix: prefix}
+	if err := s.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Lookup returns the value of the key prefix+key
+func (s *Source) Lookup(key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	val, ok := s.values[s.prefix+key]
+	return val, ok
+}
+
+// Refresh fetches every key under the prefix again, replacing the values held by the source
+func (s *Source) Refresh(ctx context.Context) error {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		values[string(kv.Key)] = string(kv.Value)
+	}
+
+	s.mu.Lock()
+	s.values = values
+	s.rev = resp.Header.Revision
+	s.mu.Unlock()
+	return nil
+}
+
+// Watch keeps the source up to date with changes to the keys under the prefix until ctx is done. The returned
+// channel receives a value after every change, so it can be passed to ruadan.WatchTrigger to reload the config, and
+// is closed when the watch ends. The watch starts right after the last fetch, so no change in between is missed.
+// When etcd has compacted away the revisions the watch needs, every key is fetched again and the watch starts over
+// from the new revision
+func (s *Source) Watch(ctx context.Context) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer close(changed)
+		for ctx.Err() == nil {
+			s.mu.RLock()
+			rev := s.rev
+			s.mu.RUnlock()
+
+			if s.watch(ctx, rev, changed) {
+				// the watch ended without ctx being done, e.g. the client was closed, so try again shortly
+				wait(ctx)
+				continue
+			}
+
+			// the watch needs a revision that has been compacted away, so fetch everything again
+			if err := s.Refresh(ctx); err != nil {
+				wait(ctx)
+				continue
+			}
+			notify(changed)
+		}
+	}()
+
+	return changed
+}
+
+// retryDelay is how long Watch waits before watching or fetching the keys again after either fails
+const retryDelay = time.Second
+
+// wait waits for retryDelay or until ctx is done
+func wait(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(retryDelay):
+	}
+}
+
+// watch applies the changes after rev until ctx is done or the watch ends, reporting false when it ended because rev
+// was compacted
+func (s *Source) watch(ctx context.Context, rev int64, changed chan<- struct{}) bool {
+	wctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for resp := range s.client.Watch(wctx, s.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+		if resp.CompactRevision != 0 || errors.Is(resp.Err(), rpctypes.ErrCompacted) {
+			return false
+		}
+		if resp.Err() != nil {
+			continue
+		}
+
+		s.mu.Lock()
+		for _, ev := range resp.Events {
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				s.values[string(ev.Kv.Key)] = string(ev.Kv.Value)
+			case clientv3.EventTypeDelete:
+				delete(s.values, string(ev.Kv.Key))
+			}
+		}
+		if resp.Header.Revision > s.rev {
+			s.rev = resp.Header.Revision
+		}
+		s.mu.Unlock()
+
+		notify(changed)
+	}
+
+	return true
+}
+
+// notify signals a change without blocking, as one pending signal is enough to reload
+func notify(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
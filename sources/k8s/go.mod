@@ -0,0 +1,7 @@
+module github.com/bit-cmdr/ruadan/sources/k8s
+
+go 1.14
+
+require github.com/bit-cmdr/ruadan v0.0.0-00010101000000-000000000000
+
+replace github.com/bit-cmdr/ruadan => ../..
@@ -0,0 +1,66 @@
+// Package k8s reads ruadan config values from Kubernetes ConfigMap and Secret volumes. It lives in its own module so
+// the core ruadan module stays free of dependencies
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bit-cmdr/ruadan"
+)
+
+// MountSource is a ruadan.Source that reads values from the directories a ConfigMap or Secret is mounted at, where
+// every key is a file holding its value. Directories are checked in order
+type MountSource struct {
+	Dirs []string
+}
+
+var _ ruadan.CheckedSource = MountSource{}
+
+// New creates a MountSource reading from dirs, e.g. /etc/config and /etc/secrets
+func New(dirs ...string) MountSource {
+	return MountSource{Dirs: dirs}
+}
+
+// Name returns k8s, the name the MountSource is reported with by ruadan.Loader.Sources
+func (m MountSource) Name() string {
+	return "k8s"
+}
+
+// Check reports an error when none of the directories exist, usually because the volume isn't mounted
+func (m MountSource) Check(ctx context.Context) error {
+	for _, dir := range m.Dirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("k8s: none of %s are mounted", strings.Join(m.Dirs, ", "))
+}
+
+// Lookup reads the file named by key from the first directory that has it. Keys are tried as they are, lower-cased,
+// e.g. test_int, and lower-cased with dashes, e.g. test-int, to match the usual ConfigMap key styles. The trailing
+// newline many tools add to secrets is trimmed
+func (m MountSource) Lookup(key string) (string, bool) {
+	if key == "" || strings.ContainsAny(key, `/\`) {
+		return "", false
+	}
+
+	names := []string{key, strings.ToLower(key), strings.ReplaceAll(strings.ToLower(key), "_", "-")}
+	for _, dir := range m.Dirs {
+		for _, name := range names {
+			b, err := ioutil.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				continue
+			}
+
+			return strings.TrimRight(string(b), "\r\n"), true
+		}
+	}
+
+	return "", false
+}
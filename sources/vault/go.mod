@@ -0,0 +1,7 @@
+module github.com/bit-cmdr/ruadan/sources/vault
+
+go 1.16
+
+require github.com/bit-cmdr/ruadan v0.0.0-00010101000000-000000000000
+
+replace github.com/bit-cmdr/ruadan => ../..
@@ -0,0 +1,55 @@
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bit-cmdr/ruadan"
+)
+
+// Transit is a ruadan.KeyProvider that decrypts with a key of the Vault transit secrets engine, so the key never
+// leaves Vault. Ciphertext is what transit/encrypt returns, e.g. vault:v1:..., or its base64 part alone, which is read
+// as version 1 of the key
+type Transit struct {
+	// Mount is the path the transit engine is mounted at, transit by default
+	Mount string
+	// Key is the name of the transit key
+	Key string
+
+	source *Source
+}
+
+var _ ruadan.KeyProvider = (*Transit)(nil)
+
+// Transit creates a Transit decrypting with the transit key named key, using the address, token and client of s
+func (s *Source) Transit(key string) *Transit {
+	return &Transit{Mount: "transit", Key: key, source: s}
+}
+
+// Decrypt sends ciphertext to transit/decrypt and returns the plaintext
+func (t *Transit) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	c := string(ciphertext)
+	if !strings.HasPrefix(c, "vault:") {
+		c = "vault:v1:" + base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	path := strings.Trim(t.Mount, "/") + "/decrypt/" + t.Key
+	if err := t.source.do(ctx, http.MethodPost, path, map[string]string{"ciphertext": c}, &resp); err != nil {
+		return nil, err
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("vault: %s: invalid plaintext: %v", path, err)
+	}
+
+	return plaintext, nil
+}
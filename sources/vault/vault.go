@@ -0,0 +1,247 @@
+// Package vault reads ruadan config values from HashiCorp Vault KV v2 secrets, so secrets never have to pass through
+// env vars. It talks to the Vault HTTP API directly and lives in its own module so the core ruadan module stays free
+// of dependencies
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bit-cmdr/ruadan"
+)
+
+// Tag is the struct tag read by the Source, holding the secret path and the key within it, e.g.
+// `vault:"secret/data/app#password"`
+const Tag = "vault"
+
+// Source is a ruadan.TagSource that resolves fields tagged with vault: from KV v2 secrets. Each secret is fetched
+// once and cached for CacheTTL, so a struct with many fields from the same secret makes a single request
+type Source struct {
+	// Addr is the address of the Vault server, e.g. https://vault.example.com:8200
+	Addr string
+	// Token is the Vault token used for every request
+	Token string
+	// Client is the http.Client used for requests, http.DefaultClient when nil
+	Client *http.Client
+	// CacheTTL is how long a fetched secret is reused for, so a reload picks up rotated secrets. Zero caches forever
+	CacheTTL time.Duration
+	// Errors receives the errors of failed requests, by default they are written to the standard logger
+	Errors func(error)
+
+	mu    sync.Mutex
+	cache map[string]secret
+}
+
+type secret struct {
+	data    map[string]string
+	fetched time.Time
+}
+
+var (
+	_ ruadan.TagSource     = (*Source)(nil)
+	_ ruadan.CheckedSource = (*Source)(nil)
+)
+
+// New creates a Source for the Vault server at addr using token, caching secrets for 5 minutes
+func New(addr, token string) *Source {
+	return &Source{Addr: addr, Token: token, CacheTTL: 5 * time.Minute}
+}
+
+// FromEnv creates a Source from the VAULT_ADDR and VAULT_TOKEN env vars used by the Vault cli
+func FromEnv() *Source {
+	return New(os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN"))
+}
+
+// Tag returns the name of the struct tag read by the Source
+func (s *Source) Tag() string {
+	return Tag
+}
+
+// Name returns vault, the name the Source is reported with by ruadan.Loader.Sources
+func (s *Source) Name() string {
+	return "vault"
+}
+
+// Check reports whether the Vault server can be reached and is unsealed. Standby nodes count as reachable
+func (s *Source) Check(ctx context.Context) error {
+	var health struct {
+		Sealed bool `json:"sealed"`
+	}
+	return s.do(ctx, http.MethodGet, "sys/health?standbyok=true", nil, &health)
+}
+
+// Lookup reports false for every key, fields are only read through their vault: tag
+func (s *Source) Lookup(key string) (string, bool) {
+	return "", false
+}
+
+// LookupTag reads the key after the # from the secret at the path before it. A failed request is passed to Errors
+// and reported as not found, so the field falls through to the next layer
+func (s *Source) LookupTag(ctx context.Context, value string) (string, bool) {
+	i := strings.LastIndex(value, "#")
+	if i < 0 {
+		s.report(fmt.Errorf("vault: tag %q is missing a #key", value))
+		return "", false
+	}
+
+	data, err := s.secret(ctx, strings.Trim(value[:i], "/"))
+	if err != nil {
+		s.report(err)
+		return "", false
+	}
+
+	val, ok := data[value[i+1:]]
+	return val, ok
+}
+
+// Invalidate drops every cached secret, so the next lookup of each is fetched again
+func (s *Source) Invalidate() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
+
+func (s *Source) secret(ctx context.Context, path string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, ok := s.cache[path]; ok && (s.CacheTTL == 0 || time.Since(c.fetched) < s.CacheTTL) {
+		return c.data, nil
+	}
+
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := s.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string, len(resp.Data.Data))
+	for k, v := range resp.Data.Data {
+		if str, ok := v.(string); ok {
+			data[k] = str
+			continue
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("vault: %s#%s: %v", path, k, err)
+		}
+		data[k] = string(b)
+	}
+
+	if s.cache == nil {
+		s.cache = map[string]secret{}
+	}
+	s.cache[path] = secret{data: data, fetched: time.Now()}
+	return data, nil
+}
+
+// RenewToken renews the token and returns its new TTL. A TTL of 0 means the token never expires
+func (s *Source) RenewToken(ctx context.Context) (time.Duration, error) {
+	var resp struct {
+		Auth struct {
+			LeaseDuration int  `json:"lease_duration"`
+			Renewable     bool `json:"renewable"`
+		} `json:"auth"`
+	}
+	if err := s.do(ctx, http.MethodPost, "auth/token/renew-self", nil, &resp); err != nil {
+		return 0, err
+	}
+
+	if resp.Auth.LeaseDuration > 0 && !resp.Auth.Renewable {
+		return 0, errors.New("vault: token is not renewable")
+	}
+
+	return time.Duration(resp.Auth.LeaseDuration) * time.Second, nil
+}
+
+// KeepTokenAlive renews the token every time half of its TTL has passed until ctx is done or the token can't be
+// renewed any more. Failed renewals are passed to Errors and retried after a minute
+func (s *Source) KeepTokenAlive(ctx context.Context) {
+	for {
+		wait := time.Minute
+		ttl, err := s.RenewToken(ctx)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			s.report(err)
+		case ttl == 0:
+			return
+		default:
+			wait = ttl / 2
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// do sends a request to the Vault API with in as its JSON body, unless it is nil, and decodes the response into out
+func (s *Source) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("vault: %s: %v", path, err)
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(s.Addr, "/")+"/v1/"+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", s.Token)
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&body)
+		return fmt.Errorf("vault: %s: %s %s", path, resp.Status, strings.Join(body.Errors, ", "))
+	}
+
+	if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("vault: %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func (s *Source) report(err error) {
+	if s.Errors != nil {
+		s.Errors(err)
+		return
+	}
+
+	log.Println("ruadan", err)
+}
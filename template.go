@@ -0,0 +1,187 @@
+package ruadan
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// WithTemplates renders string values holding Go template actions once every layer is merged, with the config struct
+// as the data, e.g. ADDR='{{ .Host }}:{{ .Port }}' for a derived address. Nested fields are reached by their path,
+// such as {{ .Server.Host }}. Values that refer to other templated values are rendered after them, and fields that
+// refer to each other in a loop are returned as a FieldError, as are templates that fail to parse or run. Templates
+// are rendered after WithExpand and before any Resolver
+func WithTemplates() LoadOptions {
+	return func(o *LoadOption) { o.templates = true }
+}
+
+// templateState is how far a templated field has got in renderer.render
+type templateState int
+
+const (
+	templatePending templateState = iota
+	templateRendering
+	templateDone
+	templateFailed
+)
+
+// renderer renders the templated fields of a load in the order they refer to each other
+type renderer struct {
+	data   interface{}
+	fields map[string]*templateField
+	// paths holds the paths of the fields in the order of the struct
+	paths []string
+	// stack holds the paths of the fields being rendered, to name a loop
+	stack []string
+	errs  []error
+}
+
+type templateField struct {
+	meta  fieldMeta
+	tmpl  *template.Template
+	deps  []string
+	state templateState
+}
+
+// applyTemplates renders every string field of cfg that holds a template action, returning a FieldError for each one
+// that fails
+func applyTemplates(cfg interface{}, metas []fieldMeta) error {
+	r := &renderer{data: cfg, fields: map[string]*templateField{}}
+	for _, meta := range metas {
+		field := stringField(meta.Field)
+		if !field.IsValid() || !strings.Contains(field.String(), "{{") {
+			continue
+		}
+
+		path := fieldPath(meta)
+		tmpl, err := template.New(path).Option("missingkey=error").Parse(field.String())
+		if err != nil {
+			r.errs = append(r.errs, newFieldError(meta, field.String(), err))
+			continue
+		}
+
+		r.fields[path] = &templateField{meta: meta, tmpl: tmpl, deps: templateRefs(tmpl.Root)}
+		r.paths = append(r.paths, path)
+	}
+
+	for _, path := range r.paths {
+		r.render(path)
+	}
+
+	return joinErrors(r.errs)
+}
+
+// render renders the field at path after the templated fields it refers to, reporting false when it or one of them
+// failed
+func (r *renderer) render(path string) bool {
+	f := r.fields[path]
+	switch f.state {
+	case templateDone:
+		return true
+	case templateFailed:
+		return false
+	case templateRendering:
+		i := len(r.stack) - 1
+		for i > 0 && r.stack[i] != path {
+			i--
+		}
+		loop := strings.Join(append(r.stack[i:len(r.stack):len(r.stack)], path), " -> ")
+		r.errs = append(r.errs, newFieldError(f.meta, stringField(f.meta.Field).String(),
+			fmt.Errorf("template reference loop: %s", loop)))
+		f.state = templateFailed
+		return false
+	}
+
+	f.state = templateRendering
+	r.stack = append(r.stack, path)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+
+	for _, dep := range r.depends(f) {
+		if !r.render(dep) {
+			// the field it refers to has already been reported
+			f.state = templateFailed
+			return false
+		}
+	}
+
+	field := stringField(f.meta.Field)
+	var b strings.Builder
+	if err := f.tmpl.Execute(&b, r.data); err != nil {
+		r.errs = append(r.errs, newFieldError(f.meta, field.String(), err))
+		f.state = templateFailed
+		return false
+	}
+
+	field.SetString(b.String())
+	f.state = templateDone
+	return true
+}
+
+// depends returns the paths of the templated fields f refers to, including every templated field under a nested
+// struct it refers to as a whole
+func (r *renderer) depends(f *templateField) []string {
+	deps := []string{}
+	for _, ref := range f.deps {
+		for _, path := range r.paths {
+			if path == ref || strings.HasPrefix(path, ref+".") {
+				deps = append(deps, path)
+			}
+		}
+	}
+
+	return deps
+}
+
+// templateRefs returns the field paths a template refers to, e.g. Server.Host for {{ .Server.Host }}. Fields used
+// inside range or with, where dot is something else, are included too, which at worst renders a field early
+func templateRefs(node parse.Node) []string {
+	refs := []string{}
+	var walk func(parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, c := range n.Nodes {
+					walk(c)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, c := range n.Cmds {
+					walk(c)
+				}
+			}
+		case *parse.CommandNode:
+			for _, a := range n.Args {
+				walk(a)
+			}
+		case *parse.FieldNode:
+			refs = append(refs, strings.Join(n.Ident, "."))
+		case *parse.VariableNode:
+			// $ is the config struct wherever it is used, so $.Server.Host is Server.Host
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				refs = append(refs, strings.Join(n.Ident[1:], "."))
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		}
+	}
+	walk(node)
+
+	return refs
+}
@@ -0,0 +1,109 @@
+package ruadan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type templateServer struct {
+	Host string `envconfig:"HOST" default:"localhost"`
+	Port int    `envconfig:"PORT" default:"8080"`
+}
+
+type templateConfig struct {
+	Server templateServer
+	Addr   string `envconfig:"ADDR"`
+	URL    string `envconfig:"URL"`
+	Name   string `envconfig:"NAME"`
+}
+
+func TestTemplates(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    templateConfig
+		wantErr string
+	}{
+		{
+			name: "nested fields",
+			env:  map[string]string{"ADDR": "{{ .Server.Host }}:{{ .Server.Port }}"},
+			want: templateConfig{Server: templateServer{Host: "localhost", Port: 8080}, Addr: "localhost:8080"},
+		},
+		{
+			name: "rendered in dependency order",
+			env: map[string]string{
+				"URL":         "http://{{ .Addr }}/",
+				"ADDR":        "{{ .Server.Host }}:{{ .Server.Port }}",
+				"SERVER_HOST": "{{ .Name }}.internal",
+				"NAME":        "api",
+			},
+			want: templateConfig{
+				Server: templateServer{Host: "api.internal", Port: 8080},
+				Addr:   "api.internal:8080",
+				URL:    "http://api.internal:8080/",
+				Name:   "api",
+			},
+		},
+		{
+			name: "dollar",
+			env:  map[string]string{"URL": "{{ $.Addr }}", "ADDR": "{{ .Name }}", "NAME": "api"},
+			want: templateConfig{
+				Server: templateServer{Host: "localhost", Port: 8080},
+				Addr:   "api",
+				URL:    "api",
+				Name:   "api",
+			},
+		},
+		{
+			name:    "loop",
+			env:     map[string]string{"ADDR": "{{ .URL }}", "URL": "{{ .Addr }}"},
+			wantErr: "template reference loop: Addr -> URL -> Addr",
+		},
+		{
+			name:    "parse error",
+			env:     map[string]string{"ADDR": "{{ .Server.Host"},
+			wantErr: "ADDR",
+		},
+		{
+			name:    "missing field",
+			env:     map[string]string{"ADDR": "{{ .Nope }}"},
+			wantErr: "Nope",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg templateConfig
+			err := testLoad(&cfg, tt.env, nil, nil, WithTemplates())
+			switch {
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				var fe *FieldError
+				if !errors.As(err, &fe) {
+					t.Errorf("expected a FieldError, got %T", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case cfg != tt.want:
+				t.Errorf("rendered %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplatesAfterExpand(t *testing.T) {
+	var cfg struct {
+		Host string `envconfig:"HOST"`
+		URL  string `envconfig:"URL"`
+	}
+	env := map[string]string{"HOST": "${REGION}.example.com", "REGION": "eu", "URL": "https://{{ .Host }}/"}
+	if err := testLoad(&cfg, env, nil, nil, WithExpand(), WithTemplates()); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.URL != "https://eu.example.com/" {
+		t.Errorf("rendered %q", cfg.URL)
+	}
+}
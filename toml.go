@@ -0,0 +1,332 @@
+package ruadan
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads a subset of TOML into a flat map keyed by the dotted path of each value. Tables, dotted keys,
+// inline tables, basic and literal strings (including multi-line), numbers, booleans, dates and arrays of scalars are
+// supported. Arrays are joined with commas so they can be read the same way slices are read from env and cli. Arrays
+// of tables are not supported
+func parseTOML(r io.Reader) (map[string]string, error) {
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]string{}
+	table := ""
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(stripTOMLComment(lines[i]))
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "[["):
+			return nil, fmt.Errorf("toml: line %d: arrays of tables are not supported", i+1)
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("toml: line %d: unterminated table header", i+1)
+			}
+			table = tomlKey(line[1 : len(line)-1])
+			continue
+		}
+
+		eq := tomlKeySeparator(line)
+		if eq < 0 {
+			return nil, fmt.Errorf("toml: line %d: expected key = value", i+1)
+		}
+
+		key := tomlKey(line[:eq])
+		if table != "" {
+			key = table + "." + key
+		}
+		value := strings.TrimSpace(line[eq+1:])
+
+		// multi-line strings and arrays continue until they're closed
+		for _, delim := range []string{`"""`, `'''`} {
+			if !strings.HasPrefix(value, delim) {
+				continue
+			}
+			for (len(value) < 6 || !strings.HasSuffix(value, delim)) && i+1 < len(lines) {
+				i++
+				value += "\n" + strings.TrimRight(lines[i], " \t")
+			}
+		}
+		for strings.HasPrefix(value, "[") && !tomlBalanced(value) && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripTOMLComment(lines[i]))
+		}
+
+		err = setTOMLValue(values, key, value)
+		if err != nil {
+			return nil, fmt.Errorf("toml: line %d: %v", i+1, err)
+		}
+	}
+
+	return values, nil
+}
+
+func setTOMLValue(values map[string]string, key, value string) error {
+	switch {
+	case strings.HasPrefix(value, "{"):
+		if !strings.HasSuffix(value, "}") {
+			return fmt.Errorf("unterminated inline table")
+		}
+
+		for _, pair := range splitTOMLList(value[1 : len(value)-1]) {
+			eq := tomlKeySeparator(pair)
+			if eq < 0 {
+				return fmt.Errorf("expected key = value in inline table")
+			}
+			err := setTOMLValue(values, key+"."+tomlKey(pair[:eq]), strings.TrimSpace(pair[eq+1:]))
+			if err != nil {
+				return err
+			}
+		}
+	case strings.HasPrefix(value, "["):
+		if !strings.HasSuffix(value, "]") {
+			return fmt.Errorf("unterminated array")
+		}
+
+		items := []string{}
+		for _, item := range splitTOMLList(value[1 : len(value)-1]) {
+			if strings.HasPrefix(item, "{") || strings.HasPrefix(item, "[") {
+				return fmt.Errorf("only arrays of scalars are supported")
+			}
+
+			v, err := tomlScalar(item)
+			if err != nil {
+				return err
+			}
+			items = append(items, v)
+		}
+		values[key] = strings.Join(items, ",")
+	default:
+		v, err := tomlScalar(value)
+		if err != nil {
+			return err
+		}
+		values[key] = v
+	}
+
+	return nil
+}
+
+func tomlScalar(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"""`) && strings.HasSuffix(s, `"""`) && len(s) >= 6:
+		v, err := unescapeTOML(strings.TrimPrefix(s[3:len(s)-3], "\n"), true)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s: %v", s, err)
+		}
+		return v, nil
+	case strings.HasPrefix(s, `'''`) && strings.HasSuffix(s, `'''`) && len(s) >= 6:
+		return strings.TrimPrefix(s[3:len(s)-3], "\n"), nil
+	case strings.HasPrefix(s, `"`):
+		if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		v, err := unescapeTOML(s[1:len(s)-1], false)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s: %v", s, err)
+		}
+		return v, nil
+	case strings.HasPrefix(s, `'`):
+		if len(s) < 2 || !strings.HasSuffix(s, `'`) {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case s == "":
+		return "", fmt.Errorf("missing value")
+	default:
+		// numbers can use underscores between digits, everything else (bools, dates) is kept as written
+		if s[0] == '+' || s[0] == '-' || (s[0] >= '0' && s[0] <= '9') {
+			return strings.ReplaceAll(s, "_", ""), nil
+		}
+		return s, nil
+	}
+}
+
+// unescapeTOML decodes the escapes of the body of a basic string. In the body of a multi-line string, a backslash at
+// the end of a line also trims the line break and any whitespace that follows it
+func unescapeTOML(body string, multiline bool) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		if body[i] != '\\' {
+			b.WriteByte(body[i])
+			continue
+		}
+		if i++; i == len(body) {
+			return "", fmt.Errorf("trailing backslash")
+		}
+
+		switch c := body[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'u', 'U':
+			n := 4
+			if c == 'U' {
+				n = 8
+			}
+			if i+n >= len(body) {
+				return "", fmt.Errorf("short escape \\%c", c)
+			}
+			r, err := strconv.ParseUint(body[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape \\%c%s", c, body[i+1:i+1+n])
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			rest := strings.TrimLeft(body[i:], " \t")
+			if !multiline || !strings.HasPrefix(rest, "\n") {
+				return "", fmt.Errorf("invalid escape \\%c", c)
+			}
+			i = len(body) - len(strings.TrimLeft(rest, " \t\n")) - 1
+		}
+	}
+
+	return b.String(), nil
+}
+
+func tomlKey(s string) string {
+	parts := []string{}
+	for _, part := range splitTOMLOutsideQuotes(s, '.') {
+		part = strings.TrimSpace(part)
+		if v, err := tomlScalar(part); err == nil && (strings.HasPrefix(part, `"`) || strings.HasPrefix(part, `'`)) {
+			part = v
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func tomlKeySeparator(line string) int {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '=':
+			return i
+		}
+	}
+
+	return -1
+}
+
+func stripTOMLComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+func tomlBalanced(s string) bool {
+	depth := 0
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		}
+	}
+
+	return depth == 0
+}
+
+func splitTOMLList(s string) []string {
+	items := []string{}
+	for _, item := range splitTOMLOutsideQuotes(s, ',') {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// splitTOMLOutsideQuotes splits s on sep when it isn't inside quotes, brackets or braces
+func splitTOMLOutsideQuotes(s string, sep rune) []string {
+	parts := []string{}
+	depth := 0
+	start := 0
+	var quote rune
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
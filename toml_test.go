@@ -0,0 +1,80 @@
+package ruadan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "comment after an escaped quote",
+			data: `a = "x\" # y" # z` + "\n",
+			want: map[string]string{"a": `x" # y`},
+		},
+		{
+			name: "escaped quote in an array",
+			data: `tags = ["a\", b", "c"] # d` + "\n",
+			want: map[string]string{"tags": `a", b,c`},
+		},
+		{
+			name: "escaped backslash before a comment",
+			data: `path = "c:\\" # dir` + "\n",
+			want: map[string]string{"path": `c:\`},
+		},
+		{
+			name: "unicode escapes",
+			data: `a = "caf\u00e9 \U0001F600"` + "\n",
+			want: map[string]string{"a": "café 😀"},
+		},
+		{
+			name: "multi-line basic string",
+			data: "a = \"\"\"\nline \"one\"\\tx\nline \\\"two\\\" \\\\\n\"\"\"\n",
+			want: map[string]string{"a": "line \"one\"\tx\nline \"two\" \\\n"},
+		},
+		{
+			name: "multi-line line ending backslash",
+			data: "a = \"\"\"\nthe quick \\\n    brown \\\n\n    fox\"\"\"\n",
+			want: map[string]string{"a": "the quick brown fox"},
+		},
+		{
+			name: "multi-line literal string",
+			data: "a = '''\nc:\\dir \"x\"\n'''\n",
+			want: map[string]string{"a": "c:\\dir \"x\"\n"},
+		},
+		{
+			name:    "invalid escape",
+			data:    `a = "x\q"` + "\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid escape in a multi-line string",
+			data:    "a = \"\"\"\nx\\q\n\"\"\"\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML(strings.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
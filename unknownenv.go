@@ -0,0 +1,170 @@
+package ruadan
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// WithUnknownEnv reports every env whose name starts with prefix, e.g. MYAPP, but that no field reads, so a typo like
+// MYAPP_TIMEOT doesn't go unnoticed. At LevelError each one is returned as a *FieldError wrapping ErrUnknownKey,
+// otherwise it is emitted as a Diagnostic at level. The names come from the env and dotenv files, along with any
+// MapSource, and the env names of fields, the aliases in their envconfig: tags, their deprecated: names and the
+// numbered envs of slices are all known
+func WithUnknownEnv(prefix string, level Level) LoadOptions {
+	return func(o *LoadOption) {
+		o.unknownEnv = envify(strings.TrimSuffix(prefix, "_"))
+		o.unknownLevel = level
+	}
+}
+
+// keys lists the names of every env var
+func (EnvSource) keys() []string {
+	environ := os.Environ()
+	names := make([]string, 0, len(environ))
+	for _, kv := range environ {
+		if name, _, _ := strings.Cut(kv, "="); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// keys lists the names in the map
+func (m MapSource) keys() []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// keys lists the names of every source that can list them, without duplicates
+func (srcs sources) keys() []string {
+	seen := map[string]bool{}
+	names := []string{}
+	for _, s := range srcs {
+		l, ok := s.(interface{ keys() []string })
+		if !ok {
+			continue
+		}
+
+		for _, name := range l.keys() {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// checkUnknownEnv reports the names in srcs starting with the prefix set by WithUnknownEnv that none of metas reads,
+// as errors at LevelError and as a Diagnostic below it. With WithFuzzyEnv names are compared once normalised, the
+// same as they are matched
+func checkUnknownEnv(srcs sources, opt *LoadOption, metas []fieldMeta, report func(Diagnostic)) error {
+	prefix, level := opt.unknownEnv, opt.unknownLevel
+	if prefix == "" {
+		return nil
+	}
+
+	known := map[string]bool{}
+	numbered := []string{}
+	for _, meta := range metas {
+		known[tagENV(meta)] = true
+		if opt.secretFiles {
+			known[tagENV(meta)+FileEnvSuffix] = true
+		}
+		for _, alias := range append(envAliases(meta), renamedMetas(meta)...) {
+			known[tagENV(alias)] = true
+		}
+		if indexedSlice(meta.Field.Type()) {
+			numbered = append(numbered, tagENV(meta)+"_")
+		}
+	}
+
+	errs := []error{}
+	for _, name := range srcs.keys() {
+		match := name
+		if opt.fuzzyEnv {
+			match = normalizeEnv(name)
+		}
+		if !strings.HasPrefix(match, prefix+"_") || known[match] || isNumbered(match, numbered) {
+			continue
+		}
+
+		msg := "no field reads it"
+		if near := closestName(match, known); near != "" {
+			msg += ", did you mean " + near + "?"
+		}
+
+		if level >= LevelError {
+			errs = append(errs, &FieldError{Key: name, Err: fmt.Errorf("%w in env, %s", ErrUnknownKey, msg)})
+		} else if report != nil {
+			report(Diagnostic{Level: level, Field: name, Message: "unknown env, " + msg})
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// isNumbered reports whether name is one of the numbered envs of a slice, e.g. HOSTS_0 for the prefix HOSTS_
+func isNumbered(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if n := strings.TrimPrefix(name, p); n != name && n != "" && strings.Trim(n, "0123456789") == "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// closestName returns the known name that is at most two edits from name, or "" when none is that close
+func closestName(name string, known map[string]bool) string {
+	best, bestDist := "", 3
+	for k := range known {
+		if d := editDistance(name, k); d < bestDist || d == bestDist && best != "" && k < best {
+			best, bestDist = k, d
+		}
+	}
+
+	return best
+}
+
+// editDistance is the number of single byte inserts, deletes and substitutions it takes to turn a into b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return prev[len(b)]
+}
+
+func minInt(n int, ns ...int) int {
+	for _, m := range ns {
+		if m < n {
+			n = m
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,147 @@
+package ruadan
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// WithName sets the program name shown in usage and parse errors, by default config. Pass filepath.Base(os.Args[0])
+// to show the name the program was run with
+func WithName(name string) LoadOptions {
+	return func(o *LoadOption) { o.name = name }
+}
+
+// WithUsage sets the banner printed above the flags by -h, -help or a bad flag, in place of the default
+// "Usage of <name>:" line, e.g. "Usage: myapp [flags] <file>\n\nmyapp converts files between formats"
+func WithUsage(banner string) LoadOptions {
+	return func(o *LoadOption) { o.usage = banner }
+}
+
+// WithExamples adds example command lines printed under the flags in the usage
+func WithExamples(lines ...string) LoadOptions {
+	return func(o *LoadOption) { o.examples = append(o.examples, lines...) }
+}
+
+// flagInfo is what the usage table shows about a flag beyond what its flag.Flag holds
+type flagInfo struct {
+	env      string
+	required bool
+	secret   bool
+}
+
+// metaFlagInfo returns the flagInfo of every field, keyed by flag name
+func metaFlagInfo(metas []fieldMeta) map[string]flagInfo {
+	info := make(map[string]flagInfo, len(metas)+1)
+	for _, meta := range metas {
+		info[tagCLI(meta)] = flagInfo{env: tagENV(meta), required: tagBool(meta.Tags, "required"), secret: isSecret(meta)}
+	}
+	info[ConfigFileFlag] = flagInfo{env: ConfigFileEnv}
+
+	return info
+}
+
+// setUsage sets the usage of fs to the banner, a table of the flags with their env, default and whether they are
+// required, then the examples
+func (opt *LoadOption) setUsage(fs *flag.FlagSet, info map[string]flagInfo) {
+	fs.Usage = func() {
+		w := fs.Output()
+		if opt.usage != "" {
+			fmt.Fprintln(w, opt.usage)
+		} else {
+			fmt.Fprintf(w, "Usage of %s:\n", fs.Name())
+		}
+
+		fmt.Fprintln(w, "\nFlags:")
+		printFlags(w, fs, info)
+
+		if len(opt.examples) > 0 {
+			fmt.Fprintln(w, "\nExamples:")
+			for _, line := range opt.examples {
+				fmt.Fprintln(w, "  "+line)
+			}
+		}
+	}
+}
+
+// printFlags writes a row for every flag of fs, in the order of flag.PrintDefaults
+func printFlags(w io.Writer, fs *flag.FlagSet, info map[string]flagInfo) {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  FLAG\tENV\tDEFAULT\tREQUIRED\tDESCRIPTION")
+	shorts := shortNames(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, alias := f.Value.(aliasValue); alias {
+			// short: aliases and -no- flags are shown on the row of the flag they stand for
+			return
+		}
+
+		typ, usage := flag.UnquoteUsage(f)
+		if v, ok := f.Value.(interface{ typeName() string }); ok && !strings.Contains(f.Usage, "`") {
+			typ = v.typeName()
+		}
+		i := info[f.Name]
+		if usage == "flag: "+f.Name+" or env: "+i.env {
+			// the generated description only repeats the flag and env
+			usage = ""
+		}
+
+		name := "-" + f.Name
+		if negatable(fs, f.Name) {
+			name = "-[no-]" + f.Name
+		}
+		if short, ok := shorts[f.Name]; ok {
+			name = "-" + short + ", " + name
+		}
+		if typ != "" {
+			name += " " + typ
+		}
+
+		required := ""
+		if i.required {
+			required = "yes"
+		}
+
+		def := usageDefault(f.DefValue)
+		if i.secret && def != "" {
+			// the default is whatever env or a file set, which is no place to print a secret
+			def = Redacted
+		}
+
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", name, i.env, def, required, usage)
+	})
+	tw.Flush()
+
+	// rows without a description would otherwise end in the padding of the columns before it
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if line != "" {
+			fmt.Fprintln(w, strings.TrimRight(line, " \n"))
+		}
+	}
+}
+
+// usageDefault returns the default shown for a flag, leaving zero values out the same as flag.PrintDefaults
+func usageDefault(def string) string {
+	if zeroDefault(def) {
+		return ""
+	}
+
+	if strings.ContainsAny(def, " \t") {
+		return fmt.Sprintf("%q", def)
+	}
+
+	return def
+}
+
+// zeroDefault reports whether def is how a flag prints a zero value
+func zeroDefault(def string) bool {
+	switch def {
+	case "", "0", "false", "0s", "[]":
+		return true
+	default:
+		return false
+	}
+}
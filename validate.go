@@ -0,0 +1,99 @@
+package ruadan
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Validator is implemented by config structs, and structs nested in them, that check their own values, e.g. that a
+// port is in range or that one field is set whenever another is. Validate is called once every layer is merged
+type Validator interface {
+	Validate() error
+}
+
+// Validate calls Validate on cfg, which must be a struct pointer, and on every struct nested in it that implements
+// Validator. Nested structs are validated before the structs they are in, and every error is returned together in a
+// LoadError, those of nested structs prefixed with the path of the struct, e.g. Server.TLS: cert has expired. Load
+// calls it for you, skipping structs switched off by their Enabled field or a selects: field
+func Validate(cfg interface{}) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrInvalidConfig
+	}
+
+	return joinErrors(validateStruct(v, "", nil))
+}
+
+// checkValidators validates cfg once it is loaded, only going into the nested structs that still have active fields
+func checkValidators(cfg interface{}, active []fieldMeta) error {
+	paths := map[string]bool{}
+	for _, meta := range active {
+		if meta.Name == enabledField {
+			// kept even when its struct is switched off
+			continue
+		}
+
+		path := ""
+		for _, p := range meta.Parents {
+			path = joinPath(path, p.Name)
+			paths[path] = true
+		}
+	}
+
+	return joinErrors(validateStruct(reflect.ValueOf(cfg), "", paths))
+}
+
+// validateStruct validates the structs nested in the struct v points to, then the struct itself. When active is set
+// only the nested structs whose paths it holds are validated
+func validateStruct(v reflect.Value, path string, active map[string]bool) []error {
+	errs := []error{}
+	s := v.Elem()
+	for i := 0; i < s.NumField(); i++ {
+		ft := s.Type().Field(i)
+		f := s.Field(i)
+		if !f.CanSet() || ignored(ft.Tag) {
+			continue
+		}
+
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+
+		if f.Kind() != reflect.Struct || f.Type() == ipNetType || parsesItself(f.Type()) {
+			continue
+		}
+
+		// an embedded struct shares the path of the struct it is in, the same as its fields
+		child := path
+		if !ft.Anonymous {
+			child = joinPath(path, ft.Name)
+			if active != nil && !active[child] {
+				continue
+			}
+		}
+
+		errs = append(errs, validateStruct(f.Addr(), child, active)...)
+	}
+
+	if val, ok := v.Interface().(Validator); ok {
+		if err := val.Validate(); err != nil {
+			if path != "" {
+				err = fmt.Errorf("%s: %w", path, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
@@ -0,0 +1,21 @@
+module github.com/bit-cmdr/ruadan/validators/playground
+
+go 1.22
+
+require (
+	github.com/bit-cmdr/ruadan v0.0.0-00010101000000-000000000000
+	github.com/go-playground/validator/v10 v10.23.0
+)
+
+require (
+	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/leodido/go-urn v1.4.0 // indirect
+	golang.org/x/crypto v0.19.0 // indirect
+	golang.org/x/net v0.21.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)
+
+replace github.com/bit-cmdr/ruadan => ../..
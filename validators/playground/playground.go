@@ -0,0 +1,74 @@
+// Package playground validates ruadan config structs with github.com/go-playground/validator once they are loaded, so
+// the validate: tags a team already uses keep working and their errors are reported along with every other problem.
+// It lives in its own module so the core ruadan module stays free of dependencies
+package playground
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bit-cmdr/ruadan"
+	"github.com/go-playground/validator/v10"
+)
+
+// With returns the LoadOptions that validates the config struct with v, or with validator.New when v is nil. It
+// replaces the validate: rules built into ruadan, as both read the same tag
+func With(v *validator.Validate) ruadan.LoadOptions {
+	if v == nil {
+		v = validator.New()
+	}
+
+	return ruadan.WithValidator(Func(v))
+}
+
+// Func returns the func passed to ruadan.WithValidator that validates the config struct with v
+func Func(v *validator.Validate) func(cfg interface{}) error {
+	return func(cfg interface{}) error {
+		return Convert(v.Struct(cfg))
+	}
+}
+
+// Convert turns validator.ValidationErrors into a *ruadan.ValidationError with a *ruadan.FieldError for each failed
+// rule, wrapping ruadan.ErrValidation. Field holds the path of the field for the load to fill in its env, flag and
+// value. Other errors, such as validator.InvalidValidationError, are returned as they are
+func Convert(err error) error {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	fields := make([]*ruadan.FieldError, len(verrs))
+	for i, fe := range verrs {
+		rule := fe.Tag()
+		if fe.Param() != "" {
+			rule += "=" + fe.Param()
+		}
+		fields[i] = &ruadan.FieldError{Field: fieldPath(fe.StructNamespace()), Err: ruleError(rule)}
+	}
+
+	return &ruadan.ValidationError{Fields: fields}
+}
+
+// fieldPath drops the name of the config struct and any index from a namespace, e.g. Config.Server.Peers[0] becomes
+// Server.Peers
+func fieldPath(ns string) string {
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		ns = ns[i+1:]
+	}
+	if i := strings.IndexByte(ns, '['); i >= 0 {
+		ns = ns[:i]
+	}
+
+	return ns
+}
+
+// ruleError wraps ruadan.ErrValidation with the rule a field failed
+type ruleError string
+
+func (e ruleError) Error() string {
+	return "failed the " + string(e) + " rule"
+}
+
+func (e ruleError) Unwrap() error {
+	return ruadan.ErrValidation
+}
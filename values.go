@@ -0,0 +1,289 @@
+package ruadan
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Base64Prefix marks a string or []byte value given in standard base64, e.g. base64:AAECAw==, which is decoded before
+// it is set, so binary certs and keys can be passed through env
+const Base64Prefix = "base64:"
+
+// The parsers below are shared by the reflect path and Binder, so a value reads the same however the field was
+// registered. Numbers accept the 0x, 0o and 0b prefixes. The values after them back the fields of a Binder, and
+// print their zero value when empty so flag.PrintDefaults leaves zero defaults out
+
+func parseBool(v string) (bool, error) {
+	return strconv.ParseBool(v)
+}
+
+func parseInt(v string, bits int) (int64, error) {
+	return strconv.ParseInt(v, 0, bits)
+}
+
+func parseUint(v string, bits int) (uint64, error) {
+	return strconv.ParseUint(v, 0, bits)
+}
+
+func parseFloat(v string, bits int) (float64, error) {
+	return strconv.ParseFloat(v, bits)
+}
+
+func parseDuration(v string) (time.Duration, error) {
+	return time.ParseDuration(v)
+}
+
+func parseString(v string) (string, error) {
+	b, err := parseBytes(v)
+	return string(b), err
+}
+
+// parseBytes decodes a value starting with Base64Prefix, and returns any other value as it is
+func parseBytes(v string) ([]byte, error) {
+	if !strings.HasPrefix(v, Base64Prefix) {
+		return []byte(v), nil
+	}
+
+	b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(v, Base64Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %v", err)
+	}
+
+	return b, nil
+}
+
+// decodeFlags decodes the Base64Prefix values of string fields whose flag was set on the cli or by a layer ranked
+// above it, as registerFlag leaves those to flag.StringVar, which sets them as given. A value that a base64Value kept
+// back is reported here too, so bad base64 is a FieldError whichever way the flag was registered
+func decodeFlags(fs *flag.FlagSet, metas []fieldMeta) error {
+	set := setFlags(fs)
+	errs := []error{}
+	for _, meta := range metas {
+		f, ok := set[tagCLI(meta)]
+		if !ok {
+			continue
+		}
+		if b := unwrapBase64(f.Value); b != nil {
+			if b.err != nil {
+				errs = append(errs, newFieldError(meta, b.raw, b.err))
+			}
+			continue
+		}
+
+		field := derefField(meta.Field)
+		if _, std := f.Value.(flag.Getter); !std || field.Type() != stringType {
+			continue
+		}
+
+		val, err := parseString(field.String())
+		if err != nil {
+			errs = append(errs, newFieldError(meta, field.String(), err))
+			continue
+		}
+		field.SetString(val)
+	}
+
+	return joinErrors(errs)
+}
+
+var stringType = reflect.TypeOf("")
+
+// base64Value wraps the flag.Value of a []byte field, or of a string field the flag package doesn't set itself, so a
+// value on the cli that isn't valid base64 doesn't stop the parse but is kept back for decodeFlags to report
+type base64Value struct {
+	flag.Value
+	raw string
+	err error
+}
+
+// withBase64 wraps v in a base64Value when the field it sets is a string or []byte read by a basicValue or sliceValue.
+// A plain string is left alone, as registerFlag hands it to flag.StringVar unless it is a secret or takes an enum
+func withBase64(v flag.Value, meta fieldMeta) flag.Value {
+	field := derefField(meta.Field)
+	switch {
+	case field.Type() == stringType && !isSecret(meta) && len(enumValues(meta.Tags)) == 0:
+		return v
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Uint8:
+	case field.Kind() != reflect.String:
+		return v
+	}
+
+	switch v.(type) {
+	case *basicValue, *sliceValue:
+		return &base64Value{Value: v}
+	default:
+		return v
+	}
+}
+
+// unwrapBase64 returns the base64Value under v, or nil when there isn't one
+func unwrapBase64(v flag.Value) *base64Value {
+	if s, ok := v.(*secretValue); ok {
+		v = s.Value
+	}
+	if e, ok := v.(*enumValue); ok {
+		v = e.Value
+	}
+
+	b, _ := v.(*base64Value)
+	return b
+}
+
+func (b *base64Value) Set(value string) error {
+	b.raw, b.err = "", nil
+	if err := b.Value.Set(value); err != nil {
+		if !strings.HasPrefix(value, Base64Prefix) {
+			return err
+		}
+		b.raw, b.err = value, err
+	}
+
+	return nil
+}
+
+func (b *base64Value) String() string {
+	if b == nil || b.Value == nil {
+		return ""
+	}
+
+	return b.Value.String()
+}
+
+func (b *base64Value) typeName() string {
+	return valueTypeName(b.Value)
+}
+
+type stringValue struct{ p *string }
+
+func (s stringValue) Set(v string) error {
+	val, err := parseString(v)
+	if err != nil {
+		return err
+	}
+	*s.p = val
+	return nil
+}
+
+func (s stringValue) String() string {
+	if s.p == nil {
+		return ""
+	}
+	return *s.p
+}
+
+type boolValue struct{ p *bool }
+
+func (b boolValue) Set(v string) error {
+	val, err := parseBool(v)
+	if err != nil {
+		return err
+	}
+	*b.p = val
+	return nil
+}
+
+func (b boolValue) String() string {
+	if b.p == nil {
+		return "false"
+	}
+	return strconv.FormatBool(*b.p)
+}
+
+func (b boolValue) IsBoolFlag() bool {
+	return true
+}
+
+type intValue struct{ p *int }
+
+func (i intValue) Set(v string) error {
+	val, err := parseInt(v, strconv.IntSize)
+	if err != nil {
+		return err
+	}
+	*i.p = int(val)
+	return nil
+}
+
+func (i intValue) String() string {
+	if i.p == nil {
+		return "0"
+	}
+	return strconv.Itoa(*i.p)
+}
+
+type int64Value struct{ p *int64 }
+
+func (i int64Value) Set(v string) error {
+	val, err := parseInt(v, 64)
+	if err != nil {
+		return err
+	}
+	*i.p = val
+	return nil
+}
+
+func (i int64Value) String() string {
+	if i.p == nil {
+		return "0"
+	}
+	return strconv.FormatInt(*i.p, 10)
+}
+
+type uintValue struct{ p *uint }
+
+func (u uintValue) Set(v string) error {
+	val, err := parseUint(v, strconv.IntSize)
+	if err != nil {
+		return err
+	}
+	*u.p = uint(val)
+	return nil
+}
+
+func (u uintValue) String() string {
+	if u.p == nil {
+		return "0"
+	}
+	return strconv.FormatUint(uint64(*u.p), 10)
+}
+
+type float64Value struct{ p *float64 }
+
+func (f float64Value) Set(v string) error {
+	val, err := parseFloat(v, 64)
+	if err != nil {
+		return err
+	}
+	*f.p = val
+	return nil
+}
+
+func (f float64Value) String() string {
+	if f.p == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*f.p, 'g', -1, 64)
+}
+
+type durationValue struct{ p *time.Duration }
+
+func (d durationValue) Set(v string) error {
+	val, err := parseDuration(v)
+	if err != nil {
+		return err
+	}
+	*d.p = val
+	return nil
+}
+
+func (d durationValue) String() string {
+	if d.p == nil {
+		return "0s"
+	}
+	return d.p.String()
+}
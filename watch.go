@@ -0,0 +1,205 @@
+package ruadan
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WatchOption holds the settings used by a Watcher
+type WatchOption struct {
+	interval time.Duration
+	trigger  <-chan struct{}
+	errors   func(error)
+	debounce time.Duration
+}
+
+// WatchOptions function used to change how a Watcher reloads
+type WatchOptions func(*WatchOption)
+
+// WatchInterval sets how often the config is reloaded, by default every 30 seconds. An interval of 0 turns the ticker
+// off, so the config is only reloaded by WatchTrigger or Reload
+func WatchInterval(d time.Duration) WatchOptions {
+	return func(o *WatchOption) { o.interval = d }
+}
+
+// WatchTrigger reloads the config every time a value is received on ch, e.g. from an fsnotify watcher on the config
+// file or a SIGHUP handler
+func WatchTrigger(ch <-chan struct{}) WatchOptions {
+	return func(o *WatchOption) { o.trigger = ch }
+}
+
+// WatchDebounce waits until no trigger has been received for quiet before reloading, so a burst of changes, such as
+// several files replaced one after the other or a batch of keys written to a remote store, is read in a single reload
+// once it has settled. Ticks that come while a reload is waiting are folded into it. By default every trigger reloads
+// right away
+func WatchDebounce(quiet time.Duration) WatchOptions {
+	return func(o *WatchOption) { o.debounce = quiet }
+}
+
+// WatchErrors sets the function that receives the errors of failed reloads. The last good config is kept when a
+// reload fails. By default errors are written to the standard logger
+func WatchErrors(fn func(error)) WatchOptions {
+	return func(o *WatchOption) { o.errors = fn }
+}
+
+// Watcher keeps a config struct up to date by loading a fresh copy from every layer and swapping it in atomically
+// when anything changed. Read the current config with Config, never from the struct originally passed to Watch
+type Watcher struct {
+	loader   *Loader
+	template reflect.Value
+	current  atomic.Value
+	reload   sync.Mutex
+
+	mu       sync.Mutex
+	onChange []func(old, new interface{})
+}
+
+// Watch loads cfg, which must be a struct pointer, then keeps reloading it until ctx is done. The values cfg holds
+// when Watch is called are the defaults of every reload, the same as they are for Load
+func (l *Loader) Watch(ctx context.Context, cfg interface{}, options ...WatchOptions) (*Watcher, error) {
+	opt := &WatchOption{
+		interval: 30 * time.Second,
+		errors:   func(err error) { log.Println("ruadan reload failed:", err) },
+	}
+	for _, o := range options {
+		o(opt)
+	}
+
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, ErrInvalidConfig
+	}
+
+	w := &Watcher{loader: l, template: cloneStruct(v)}
+	if err := l.Load(ctx, cfg); err != nil {
+		return nil, err
+	}
+	w.current.Store(cfg)
+
+	go w.watch(ctx, opt)
+	return w, nil
+}
+
+// Config returns the struct pointer holding the current config. It is replaced rather than changed on reload, so a
+// pointer returned here never changes under the caller
+func (w *Watcher) Config() interface{} {
+	return w.current.Load()
+}
+
+// OnChange registers fn to be called with the old and new config every time a reload changes a value. Callbacks run
+// one at a time, in the order they were registered, after the new config is swapped in
+func (w *Watcher) OnChange(fn func(old, new interface{})) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onChange = append(w.onChange, fn)
+}
+
+// Reload loads a fresh copy of the config and swaps it in if anything changed. It is called by the watcher on every
+// tick or trigger, and can be called directly to reload right away
+func (w *Watcher) Reload(ctx context.Context) error {
+	w.reload.Lock()
+	defer w.reload.Unlock()
+
+	next := cloneStruct(w.template).Interface()
+	if err := w.loader.Load(ctx, next); err != nil {
+		return err
+	}
+
+	old := w.current.Load()
+	if reflect.DeepEqual(old, next) {
+		return nil
+	}
+
+	w.current.Store(next)
+
+	w.mu.Lock()
+	onChange := append([]func(old, new interface{}){}, w.onChange...)
+	w.mu.Unlock()
+	for _, fn := range onChange {
+		fn(old, next)
+	}
+
+	return nil
+}
+
+func (w *Watcher) watch(ctx context.Context, opt *WatchOption) {
+	var tick <-chan time.Time
+	if opt.interval > 0 {
+		t := time.NewTicker(opt.interval)
+		defer t.Stop()
+		tick = t.C
+	}
+
+	// settled fires once the triggers have been quiet for opt.debounce, and is nil while no reload is waiting
+	var settled <-chan time.Time
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+			if settled != nil {
+				continue
+			}
+		case _, ok := <-opt.trigger:
+			if !ok {
+				// a closed trigger would fire forever, so stop listening to it
+				opt.trigger = nil
+				continue
+			}
+			if opt.debounce > 0 {
+				if timer == nil {
+					timer = time.NewTimer(opt.debounce)
+				} else {
+					// a timer that fired without being received is drained, so Reset starts from an empty channel
+					if !timer.Stop() && settled != nil {
+						<-timer.C
+					}
+					timer.Reset(opt.debounce)
+				}
+				settled = timer.C
+				continue
+			}
+		case <-settled:
+			settled = nil
+		}
+
+		if err := w.Reload(ctx); err != nil && ctx.Err() == nil && opt.errors != nil {
+			opt.errors(err)
+		}
+	}
+}
+
+// cloneStruct returns a pointer to a copy of the struct v points to. Pointers to nested structs are copied too, so
+// loading into the copy never changes the original
+func cloneStruct(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type().Elem())
+	c.Elem().Set(v.Elem())
+
+	e := c.Elem()
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		if !f.CanSet() {
+			continue
+		}
+
+		switch {
+		case f.Kind() == reflect.Struct:
+			f.Set(cloneStruct(f.Addr()).Elem())
+		case f.Kind() == reflect.Ptr && !f.IsNil() && f.Elem().Kind() == reflect.Struct:
+			f.Set(cloneStruct(f))
+		}
+	}
+
+	return c
+}
@@ -0,0 +1,225 @@
+package ruadan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// parseYAML reads a subset of YAML into a flat map keyed by the dotted path of each value. Nested mappings,
+// scalars, block sequences, flow sequences and literal/folded block scalars are supported. Sequences are joined with
+// commas so they can be read the same way slices are read from env and cli, and a sequence of mappings is an error
+func parseYAML(r io.Reader) (map[string]string, error) {
+	type level struct {
+		indent int
+		key    string
+	}
+
+	values := map[string]string{}
+	stack := []level{}
+	lines, err := readLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(lines); i++ {
+		raw := lines[i]
+		line := stripYAMLComment(raw)
+		if strings.TrimSpace(line) == "" || strings.TrimSpace(line) == "---" {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			// a sequence entry belongs to the closest key at a lower or equal indent
+			for len(stack) > 0 && stack[len(stack)-1].indent > indent {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("yaml: line %d: sequence without a key", i+1)
+			}
+
+			key := stack[len(stack)-1].key
+			item := strings.TrimSpace(strings.TrimPrefix(line, "-"))
+			if yamlKeySeparator(item) >= 0 || (item == "" && nestedYAML(lines, i, indent)) {
+				return nil, fmt.Errorf("yaml: line %d: %s: sequences of mappings are not supported", i+1, key)
+			}
+			item = unquoteYAML(item)
+			if existing, ok := values[key]; ok && existing != "" {
+				values[key] = existing + "," + item
+			} else {
+				values[key] = item
+			}
+			continue
+		}
+
+		sep := yamlKeySeparator(line)
+		if sep < 0 {
+			return nil, fmt.Errorf("yaml: line %d: expected key: value", i+1)
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+
+		key := unquoteYAML(strings.TrimSpace(line[:sep]))
+		if len(stack) > 0 {
+			key = stack[len(stack)-1].key + "." + key
+		}
+		value := strings.TrimSpace(line[sep+1:])
+
+		switch {
+		case value == "":
+			stack = append(stack, level{indent: indent, key: key})
+		case value == "|" || value == ">" || value == "|-" || value == ">-":
+			block := []string{}
+			blockIndent := -1
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				nextIndent := len(next) - len(strings.TrimLeft(next, " "))
+				if strings.TrimSpace(next) != "" && nextIndent <= indent {
+					break
+				}
+				if blockIndent < 0 && strings.TrimSpace(next) != "" {
+					blockIndent = nextIndent
+				}
+				if nextIndent >= blockIndent && blockIndent >= 0 {
+					next = next[blockIndent:]
+				} else {
+					next = strings.TrimSpace(next)
+				}
+				block = append(block, next)
+				i++
+			}
+
+			joiner := "\n"
+			if strings.HasPrefix(value, ">") {
+				joiner = " "
+			}
+			text := strings.Join(block, joiner)
+			if !strings.HasSuffix(value, "-") {
+				text = strings.TrimRight(text, "\n ") + "\n"
+			} else {
+				text = strings.TrimRight(text, "\n ")
+			}
+			values[key] = text
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			items := []string{}
+			for _, item := range splitFlowYAML(value[1 : len(value)-1]) {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, unquoteYAML(item))
+				}
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = unquoteYAML(value)
+		}
+	}
+
+	return values, nil
+}
+
+func readLines(r io.Reader) ([]string, error) {
+	lines := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	return lines, scanner.Err()
+}
+
+// nestedYAML reports whether the next line that isn't blank or a comment is indented further than indent
+func nestedYAML(lines []string, i, indent int) bool {
+	for _, next := range lines[i+1:] {
+		next = stripYAMLComment(next)
+		if strings.TrimSpace(next) != "" {
+			return len(next)-len(strings.TrimLeft(next, " ")) > indent
+		}
+	}
+
+	return false
+}
+
+// splitFlowYAML splits the items of a flow sequence on the commas that aren't inside quotes, leaving them quoted
+func splitFlowYAML(s string) []string {
+	items := []string{}
+	var quote rune
+	start := 0
+	escaped := false
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(items, s[start:])
+}
+
+func yamlKeySeparator(line string) int {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ':' && (i == len(line)-1 || line[i+1] == ' '):
+			return i
+		}
+	}
+
+	return -1
+}
+
+func stripYAMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+
+	return strings.TrimRight(line, " \t")
+}
+
+func unquoteYAML(s string) string {
+	switch {
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		if v, err := strconv.Unquote(s); err == nil {
+			return v
+		}
+		return s[1 : len(s)-1]
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	case s == "~" || s == "null":
+		return ""
+	default:
+		return s
+	}
+}
@@ -0,0 +1,65 @@
+package ruadan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "block sequence",
+			data: "tags:\n  - a\n  - \"b, c\"\n",
+			want: map[string]string{"tags": "a,b, c"},
+		},
+		{
+			name: "flow sequence with quoted commas",
+			data: "tags: [\"a,b\", c, 'd, ''e''']\n",
+			want: map[string]string{"tags": "a,b,c,d, 'e'"},
+		},
+		{
+			name: "flow sequence with escaped quote",
+			data: `tags: ["a\",b", c]` + "\n",
+			want: map[string]string{"tags": `a",b,c`},
+		},
+		{
+			name: "quoted item with a colon",
+			data: "hosts:\n  - \"db: primary\"\n  - http://example.com\n",
+			want: map[string]string{"hosts": "db: primary,http://example.com"},
+		},
+		{
+			name:    "sequence of mappings",
+			data:    "endpoints:\n  - host: a\n    port: 1\n  - host: b\n",
+			wantErr: true,
+		},
+		{
+			name:    "sequence of nested mappings",
+			data:    "endpoints:\n  -\n    host: a\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(strings.NewReader(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}